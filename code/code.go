@@ -2,6 +2,7 @@ package code
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/jonaylor89/John_Naylor_CMSC312_2019/utils"
@@ -38,6 +39,54 @@ const (
 
 	// NOP : No operation
 	NOP
+
+	// SEMINIT : create a semaphore with an initial count if it doesn't exist yet
+	SEMINIT
+
+	// WAIT : decrement a semaphore, blocking while it's zero
+	WAIT
+
+	// SIGNAL : increment a semaphore, waking up a process waiting on it
+	SIGNAL
+
+	// CWAIT : wait on a condition variable, giving up the kernel lock while waiting
+	CWAIT
+
+	// CSIGNAL : wake up a process waiting on a condition variable
+	CSIGNAL
+
+	// WAITCHILD : wait for a child process to exit and take its exit status, written `WAIT` without an operand
+	WAITCHILD
+
+	// HALT : terminate the process with an exit status
+	HALT
+
+	// PIPE : create a pipe, the read end gets the lowest free descriptor and the write end the next one
+	PIPE
+
+	// WRITE : write a value to a pipe, blocking while it's full
+	WRITE
+
+	// READ : read a value from a pipe, blocking while it's empty and there are writers
+	READ
+
+	// CLOSE : close a file descriptor
+	CLOSE
+
+	// LOAD : read the byte at a virtual address into the accumulator
+	LOAD
+
+	// STORE : write the accumulator to the byte at a virtual address
+	STORE
+
+	// SHMGET : create a shared memory segment with a key and a size in bytes
+	SHMGET
+
+	// SHMAT : map the shared memory segment with a key into the process
+	SHMAT
+
+	// SHMDT : unmap the shared memory segment with a key from the process
+	SHMDT
 )
 
 // Definition : definition of an instruction
@@ -56,6 +105,27 @@ var definitions = map[Opcode]*Definition{
 	SEND:  {"SEND", []int{1}},
 	RECV:  {"RECV", []int{}},
 	NOP:   {"NOP", []int{}},
+
+	SEMINIT: {"SEMINIT", []int{1, 1}},
+	WAIT:    {"WAIT", []int{1}},
+	SIGNAL:  {"SIGNAL", []int{1}},
+	CWAIT:   {"CWAIT", []int{1}},
+	CSIGNAL: {"CSIGNAL", []int{1}},
+
+	WAITCHILD: {"WAIT", []int{}},
+	HALT:      {"HALT", []int{1}},
+
+	PIPE:  {"PIPE", []int{}},
+	WRITE: {"WRITE", []int{1, 1}},
+	READ:  {"READ", []int{1}},
+	CLOSE: {"CLOSE", []int{1}},
+
+	LOAD:  {"LOAD", []int{2}},
+	STORE: {"STORE", []int{2}},
+
+	SHMGET: {"SHMGET", []int{1, 2}},
+	SHMAT:  {"SHMAT", []int{1}},
+	SHMDT:  {"SHMDT", []int{1}},
 }
 
 // Lookup : associate a opcode with its definition
@@ -84,6 +154,9 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 	case 1:
 		// Append operand at the end of the opcode
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		// Append both operands at the end of the opcode
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
@@ -138,6 +211,8 @@ func Make(op Opcode, operands ...int) []byte {
 		switch width {
 		case 1:
 			instruction[offset] = byte(o)
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
 		}
 
 		offset += width
@@ -146,11 +221,31 @@ func Make(op Opcode, operands ...int) []byte {
 	return instruction
 }
 
+// Width : number of bytes an instruction takes up including its operands
+func Width(op Opcode) int {
+	def, ok := definitions[op]
+	if !ok {
+		return 1
+	}
+
+	width := 1
+	for _, w := range def.OperandWidths {
+		width += w
+	}
+
+	return width
+}
+
 // ReadUint8 : read in an 8 bit unsigned integer
 func ReadUint8(ins Instructions) uint8 {
 	return uint8(ins[0])
 }
 
+// ReadUint16 : read in a 16 bit unsigned integer
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
 // ReadOperands : Get the operands of instructions
 func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 	operands := make([]int, len(def.OperandWidths))
@@ -160,6 +255,8 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 		switch width {
 		case 1:
 			operands[i] = int(ReadUint8(ins[offset:]))
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
 		}
 
 		offset += width
@@ -201,6 +298,57 @@ func Assemble(instructions [][]string) Instructions {
 		case "NOP":
 			op = Make(NOP, utils.StrToIntArray(ins[1:])...)
 			break
+		case "SEMINIT":
+			op = Make(SEMINIT, utils.StrToIntArray(ins[1:])...)
+			break
+		case "WAIT":
+			// Semaphores have a key, waiting on a child doesn't
+			if len(ins) == 1 {
+				op = Make(WAITCHILD)
+				break
+			}
+
+			op = Make(WAIT, utils.StrToIntArray(ins[1:])...)
+			break
+		case "SIGNAL":
+			op = Make(SIGNAL, utils.StrToIntArray(ins[1:])...)
+			break
+		case "CWAIT":
+			op = Make(CWAIT, utils.StrToIntArray(ins[1:])...)
+			break
+		case "CSIGNAL":
+			op = Make(CSIGNAL, utils.StrToIntArray(ins[1:])...)
+			break
+		case "HALT":
+			op = Make(HALT, utils.StrToIntArray(ins[1:])...)
+			break
+		case "PIPE":
+			op = Make(PIPE, utils.StrToIntArray(ins[1:])...)
+			break
+		case "WRITE":
+			op = Make(WRITE, utils.StrToIntArray(ins[1:])...)
+			break
+		case "READ":
+			op = Make(READ, utils.StrToIntArray(ins[1:])...)
+			break
+		case "CLOSE":
+			op = Make(CLOSE, utils.StrToIntArray(ins[1:])...)
+			break
+		case "LOAD":
+			op = Make(LOAD, utils.StrToIntArray(ins[1:])...)
+			break
+		case "STORE":
+			op = Make(STORE, utils.StrToIntArray(ins[1:])...)
+			break
+		case "SHMGET":
+			op = Make(SHMGET, utils.StrToIntArray(ins[1:])...)
+			break
+		case "SHMAT":
+			op = Make(SHMAT, utils.StrToIntArray(ins[1:])...)
+			break
+		case "SHMDT":
+			op = Make(SHMDT, utils.StrToIntArray(ins[1:])...)
+			break
 		default:
 			op = Make(NOP, utils.StrToIntArray(ins[1:])...)
 			break
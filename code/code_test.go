@@ -10,6 +10,9 @@ func TestMake(t *testing.T) {
 	}{
 		{CALC, []int{130}, []byte{byte(CALC), 130}},
 		{IO, []int{255}, []byte{byte(IO), 255}},
+		{SEMINIT, []int{3, 7}, []byte{byte(SEMINIT), 3, 7}},
+		{LOAD, []int{65534}, []byte{byte(LOAD), 255, 254}},
+		{SHMGET, []int{2, 1024}, []byte{byte(SHMGET), 2, 4, 0}},
 	}
 
 	for _, tt := range tests {
@@ -57,6 +60,9 @@ func TestReadOperands(t *testing.T) {
 	}{
 		{CALC, []int{130}, 1},
 		{IO, []int{255}, 1},
+		{SEMINIT, []int{3, 7}, 2},
+		{STORE, []int{300}, 2},
+		{SHMGET, []int{1, 512}, 3},
 	}
 
 	for _, tt := range tests {
@@ -84,10 +90,36 @@ func TestAssemble(t *testing.T) {
 	instructions := [][]string{
 		[]string{"CALC", "32"},
 		[]string{"IO", "14"},
+		[]string{"SEMINIT", "1", "5"},
+		[]string{"WAIT", "1"},
+		[]string{"SIGNAL", "1"},
+		[]string{"CWAIT", "2"},
+		[]string{"CSIGNAL", "2"},
+		[]string{"WAIT"},
+		[]string{"HALT", "3"},
+		[]string{"PIPE"},
+		[]string{"WRITE", "1", "9"},
+		[]string{"READ", "0"},
+		[]string{"CLOSE", "1"},
+		[]string{"LOAD", "300"},
+		[]string{"STORE", "7"},
 	}
 
 	expected := `0000 CALC 32
 0002 IO 14
+0004 SEMINIT 1 5
+0007 WAIT 1
+0009 SIGNAL 1
+0011 CWAIT 2
+0013 CSIGNAL 2
+0015 WAIT
+0016 HALT 3
+0018 PIPE
+0019 WRITE 1 9
+0022 READ 0
+0024 CLOSE 1
+0026 LOAD 300
+0029 STORE 7
 `
 
 	program := Assemble(instructions)
@@ -97,3 +129,21 @@ func TestAssemble(t *testing.T) {
 			expected, program.String())
 	}
 }
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		op    Opcode
+		width int
+	}{
+		{CALC, 2},
+		{RECV, 1},
+		{SEMINIT, 3},
+		{LOAD, 3},
+	}
+
+	for _, tt := range tests {
+		if w := Width(tt.op); w != tt.width {
+			t.Errorf("wrong width for %d. want=%d, got=%d", tt.op, tt.width, w)
+		}
+	}
+}
@@ -10,12 +10,48 @@ import (
 
 // ProcChanSize: 1000
 // Sched:
+//   Algorithm: rr
 //   TimeQuantum: 50
+//   Cascade: false
+//   Deadlock:
+//     Mode: detect
+//     Interval: 500
+//     Recovery: none
 // CPU:
+//   Count: 2
 //   ClockSpeed: 10
+// IO:
+//   Devices: 2
+//   ClockSpeed: 10
+// IPC:
+//   Mailboxes: 10
+//   MailboxSize: 10
+//   PipeSize: 16
 // Memory:
 //   PageSize: 32
 //   TotalRam: 4096
+//   Allocation: paging
+//   Fit: first
+//   Compaction: true
+//   Segmentation: false
+//   KernelMemory: true
+//   FaultLatency: 1000000
+//   Replacement: fifo
+//   Trace: trace.txt
+//   Scope: global
+//   AccessTime: 100
+//   SwapFile: swap.bin
+//   Thrashing:
+//     Window: 100
+//     Interval: 500
+//     Lower: 0.02
+//     Upper: 0.2
+//   TLB:
+//     Entries: 16
+//     Ways: 4
+//     Replacement: lru
+//     ASID: true
+//     LookupTime: 1
 //
 // Note: struct fields must be public in order for unmarshal to
 // correctly populate the data.
@@ -24,25 +60,96 @@ type Config struct {
 	MinimumFreeFrames int     `yaml:"MinimumFreeFrames"`
 	Sched             *Sched  `yaml:"Sched"`
 	CPU               *CPU    `yaml:"CPU"`
+	IO                *IO     `yaml:"IO"`
+	IPC               *IPC    `yaml:"IPC"`
 	Memory            *Memory `yaml:"Memory"`
 }
 
 // Sched : Scheduler configurations
 type Sched struct {
-	TimeQuantum int `yaml:"TimeQuantum"`
+	Algorithm       string    `yaml:"Algorithm"`
+	TimeQuantum     int       `yaml:"TimeQuantum"`
+	BalanceInterval int       `yaml:"BalanceInterval"`
+	MLFQ            *MLFQ     `yaml:"MLFQ"`
+	SJF             *SJF      `yaml:"SJF"`
+	Deadlock        *Deadlock `yaml:"Deadlock"`
+	Cascade         bool      `yaml:"Cascade"`
+}
+
+// MLFQ : Multilevel feedback queue configuration
+type MLFQ struct {
+	Levels        int   `yaml:"Levels"`
+	Quanta        []int `yaml:"Quanta"`
+	BoostInterval int   `yaml:"BoostInterval"`
+}
+
+// SJF : Shortest job first burst predictor configuration
+type SJF struct {
+	Predict         bool    `yaml:"Predict"`
+	Alpha           float64 `yaml:"Alpha"`
+	InitialEstimate float64 `yaml:"InitialEstimate"`
+}
+
+// Deadlock : Deadlock handling configuration
+type Deadlock struct {
+	Mode     string `yaml:"Mode"`
+	Interval int    `yaml:"Interval"`
+	Recovery string `yaml:"Recovery"`
 }
 
 // CPU : CPU configuration
 type CPU struct {
-	ClockSpeed1 time.Duration `yaml:"ClockSpeed1"`
-	ClockSpeed2 time.Duration `yaml:"ClockSpeed2"`
+	Count      int           `yaml:"Count"`
+	ClockSpeed time.Duration `yaml:"ClockSpeed"`
+}
+
+// IO : IO device configuration
+type IO struct {
+	Devices    int           `yaml:"Devices"`
+	ClockSpeed time.Duration `yaml:"ClockSpeed"`
+}
+
+// IPC : Interprocess communication configuration
+type IPC struct {
+	Mailboxes   int `yaml:"Mailboxes"`
+	MailboxSize int `yaml:"MailboxSize"`
+	PipeSize    int `yaml:"PipeSize"`
 }
 
 // Memory : Memory configuration
 type Memory struct {
-	PageSize  int `yaml:"PageSize"`
-	TotalRam  int `yaml:"TotalRam"`
-	CacheSize int `yaml:"CacheSize"`
+	PageSize     int           `yaml:"PageSize"`
+	TotalRam     int           `yaml:"TotalRam"`
+	Allocation   string        `yaml:"Allocation"`
+	Fit          string        `yaml:"Fit"`
+	Compaction   bool          `yaml:"Compaction"`
+	Segmentation bool          `yaml:"Segmentation"`
+	KernelMemory bool          `yaml:"KernelMemory"`
+	FaultLatency time.Duration `yaml:"FaultLatency"`
+	Replacement  string        `yaml:"Replacement"`
+	Trace        string        `yaml:"Trace"`
+	Scope        string        `yaml:"Scope"`
+	AccessTime   float64       `yaml:"AccessTime"`
+	SwapFile     string        `yaml:"SwapFile"`
+	TLB          *TLB          `yaml:"TLB"`
+	Thrashing    *Thrashing    `yaml:"Thrashing"`
+}
+
+// Thrashing : Working set and page fault frequency configuration
+type Thrashing struct {
+	Window   int     `yaml:"Window"`
+	Interval int     `yaml:"Interval"`
+	Lower    float64 `yaml:"Lower"`
+	Upper    float64 `yaml:"Upper"`
+}
+
+// TLB : Translation lookaside buffer configuration, one for each CPU
+type TLB struct {
+	Entries     int     `yaml:"Entries"`
+	Ways        int     `yaml:"Ways"`
+	Replacement string  `yaml:"Replacement"`
+	ASID        bool    `yaml:"ASID"`
+	LookupTime  float64 `yaml:"LookupTime"`
 }
 
 // ReadConfig : read config file and serialize
@@ -72,14 +179,64 @@ func ReadConfig(file string) Config {
 		log.Fatal("[ERROR] Minimum Free Frames must be above zero")
 	}
 
+	if conf.Sched.Algorithm == "" {
+		log.Fatal("[ERROR] Scheduling algorithm must be set")
+	}
+
 	if conf.Sched.TimeQuantum <= 0 {
 		log.Fatal("[ERROR] Time Quantum must be above zero")
 	}
 
-	if conf.CPU.ClockSpeed1 <= 0 {
+	if conf.Sched.BalanceInterval <= 0 {
+		log.Fatal("[ERROR] Balance interval must be above zero")
+	}
+
+	if conf.Sched.Deadlock != nil {
+		switch conf.Sched.Deadlock.Mode {
+		case "none", "detect", "avoid":
+		default:
+			log.Fatal("[ERROR] Deadlock mode must be none, detect or avoid")
+		}
+
+		if conf.Sched.Deadlock.Mode == "detect" && conf.Sched.Deadlock.Interval <= 0 {
+			log.Fatal("[ERROR] Deadlock detection interval must be above zero")
+		}
+
+		switch conf.Sched.Deadlock.Recovery {
+		case "", "none", "kill", "rollback":
+		default:
+			log.Fatal("[ERROR] Deadlock recovery must be none, kill or rollback")
+		}
+	}
+
+	if conf.CPU.Count <= 0 {
+		log.Fatal("[ERROR] CPU count must be above zero")
+	}
+
+	if conf.CPU.ClockSpeed <= 0 {
 		log.Fatal("[ERROR] ClockSpeed must be above zero")
 	}
 
+	if conf.IO.Devices < 0 {
+		log.Fatal("[ERROR] Number of IO devices can't be negative")
+	}
+
+	if conf.IO.ClockSpeed <= 0 {
+		log.Fatal("[ERROR] IO ClockSpeed must be above zero")
+	}
+
+	if conf.IPC.Mailboxes <= 0 {
+		log.Fatal("[ERROR] Number of mailboxes must be above zero")
+	}
+
+	if conf.IPC.MailboxSize <= 0 {
+		log.Fatal("[ERROR] Mailbox size must be above zero")
+	}
+
+	if conf.IPC.PipeSize <= 0 {
+		log.Fatal("[ERROR] Pipe size must be above zero")
+	}
+
 	if conf.Memory.PageSize <= 0 {
 		log.Fatal("[ERROR] Page Size must be above zero")
 	}
@@ -88,8 +245,75 @@ func ReadConfig(file string) Config {
 		log.Fatal("[ERROR] Total RAM must be above zero")
 	}
 
-	if conf.Memory.CacheSize <= 0 {
-		log.Fatal("[ERROR] Cache size must be above zero")
+	switch conf.Memory.Allocation {
+	case "", "paging", "contiguous":
+	default:
+		log.Fatal("[ERROR] Memory allocation must be paging or contiguous")
+	}
+
+	switch conf.Memory.Fit {
+	case "", "first", "best", "worst", "next":
+	default:
+		log.Fatal("[ERROR] Fit must be first, best, worst or next")
+	}
+
+	if conf.Memory.KernelMemory && conf.Memory.Allocation == "contiguous" {
+		log.Fatal("[ERROR] Kernel memory comes out of frames so it needs paging allocation")
+	}
+
+	if conf.Memory.FaultLatency < 0 {
+		log.Fatal("[ERROR] Page fault latency can't be negative")
+	}
+
+	switch conf.Memory.Replacement {
+	case "", "fifo", "lru", "clock", "lfu", "optimal":
+	default:
+		log.Fatal("[ERROR] Page replacement must be fifo, lru, clock, lfu or optimal")
+	}
+
+	// Optimal needs the future, it replays the references a previous run recorded
+	if conf.Memory.Replacement == "optimal" && conf.Memory.Trace == "" {
+		log.Fatal("[ERROR] Optimal page replacement needs a Trace recorded by a previous run")
+	}
+
+	switch conf.Memory.Scope {
+	case "", "global", "local":
+	default:
+		log.Fatal("[ERROR] Page replacement scope must be global or local")
+	}
+
+	if conf.Memory.AccessTime < 0 {
+		log.Fatal("[ERROR] Memory access time can't be negative")
+	}
+
+	if conf.Memory.TLB != nil {
+		if conf.Memory.TLB.Entries <= 0 || conf.Memory.TLB.Ways <= 0 || conf.Memory.TLB.Entries%conf.Memory.TLB.Ways != 0 {
+			log.Fatal("[ERROR] TLB entries must be a positive multiple of its ways")
+		}
+
+		switch conf.Memory.TLB.Replacement {
+		case "lru", "fifo", "random":
+		default:
+			log.Fatal("[ERROR] TLB replacement must be lru, fifo or random")
+		}
+
+		if conf.Memory.TLB.LookupTime < 0 {
+			log.Fatal("[ERROR] TLB lookup time can't be negative")
+		}
+	}
+
+	if conf.Memory.Thrashing != nil {
+		if conf.Memory.Thrashing.Window <= 0 {
+			log.Fatal("[ERROR] Working set window must be above zero")
+		}
+
+		if conf.Memory.Thrashing.Interval <= 0 {
+			log.Fatal("[ERROR] Thrashing check interval must be above zero")
+		}
+
+		if conf.Memory.Thrashing.Lower < 0 || conf.Memory.Thrashing.Lower > conf.Memory.Thrashing.Upper {
+			log.Fatal("[ERROR] Page fault frequency bounds must satisfy 0 <= Lower <= Upper")
+		}
 	}
 
 	return conf
@@ -4,7 +4,6 @@ go 1.13
 
 require (
 	github.com/gizak/termui/v3 v3.1.0
-	github.com/hashicorp/golang-lru v0.5.3
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/mattn/go-runewidth v0.0.4 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.0 // indirect
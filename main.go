@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"time"
 
 	ui "github.com/gizak/termui/v3"
 
@@ -26,17 +27,146 @@ func main() {
 	defer close(ch)
 
 	// Initialize resources
-	cpu1 := cpu.InitCPU(conf.CPU.ClockSpeed1)
-	// cpu2 := cpu.InitCPU(conf.CPU.ClockSpeed2)
-	mem := memory.InitMemory(conf.Memory.PageSize, conf.Memory.TotalRam, conf.Memory.CacheSize)
+	mem := memory.InitMemory(conf.Memory.PageSize, conf.Memory.TotalRam)
 
-	// Initialize Scheduler
-	s1 := sched.InitScheduler(cpu1, mem, ch, conf.MinimumFreeFrames, conf.Sched.TimeQuantum)
-	// s2 := sched.InitScheduler(cpu2, mem, ch, conf.MinimumFreeFrames, conf.Sched.TimeQuantum)
+	// Pick pages to replace the way the config says, optimal replays the trace of a previous run
+	if conf.Memory.Replacement != "" {
+		var refs []int
+		if conf.Memory.Replacement == "optimal" {
+			var err error
+			if refs, err = memory.ReadTrace(conf.Memory.Trace); err != nil {
+				log.Fatalf("[ERROR] %v", err)
+			}
+		}
 
-	// Run the scheduler
-	go s1.RunRoundRobin()
-	// go s1.RunFirstComeFirst
+		policy, err := memory.NewReplacementPolicy(conf.Memory.Replacement, refs)
+		if err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+
+		mem.Policy = policy
+	}
+
+	// Any other policy records the pages it touches for optimal to replay
+	if conf.Memory.Trace != "" && conf.Memory.Replacement != "optimal" {
+		trace, err := mem.AddTrace(conf.Memory.Trace)
+		if err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+		defer trace.Close()
+	}
+
+	if conf.Memory.Scope != "" {
+		mem.Scope = conf.Memory.Scope
+	}
+
+	// Pages that leave RAM go to disk
+	if conf.Memory.SwapFile != "" {
+		swap, err := mem.AddSwap(conf.Memory.SwapFile)
+		if err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+		defer swap.Close()
+	}
+
+	// Each process gets one region of RAM instead of pages
+	if conf.Memory.Allocation == "contiguous" {
+		fit := conf.Memory.Fit
+		if fit == "" {
+			fit = "first"
+		}
+
+		if _, err := mem.AddContiguous(fit, conf.Memory.Compaction); err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+	}
+
+	mem.Segmented = conf.Memory.Segmentation
+
+	// The kernel's own bookkeeping takes frames away from user pages, but never so many that no process can be admitted
+	var pcbs *memory.Cache
+	if conf.Memory.KernelMemory {
+		mem.AddBuddy(conf.MinimumFreeFrames)
+
+		var err error
+		if pcbs, err = mem.AddCache("pcb", sched.PCBSize); err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+
+		if _, err := mem.AddCache(sched.SchedulerCache, sched.SchedulerSize); err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+	}
+
+	if conf.Memory.AccessTime > 0 {
+		mem.AccessTime = conf.Memory.AccessTime
+	}
+
+	// Initialize the kernel shared by every CPU
+	k := sched.InitKernel(mem, ch, conf.MinimumFreeFrames, time.Duration(conf.Sched.BalanceInterval)*time.Millisecond)
+	k.ProcessCache = pcbs
+
+	// Give each CPU a scheduler running the policy from the config
+	for i := 0; i < conf.CPU.Count; i++ {
+		policy, err := sched.NewPolicy(conf.Sched)
+		if err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+
+		s := k.AddCPU(cpu.InitCPU(conf.CPU.ClockSpeed), policy)
+
+		// Every CPU has its own TLB in front of the shared memory
+		if conf.Memory.TLB != nil {
+			tlb, err := mem.AddTLB(conf.Memory.TLB.Entries, conf.Memory.TLB.Ways, conf.Memory.TLB.Replacement, conf.Memory.TLB.ASID)
+			if err != nil {
+				log.Fatalf("[ERROR] %v", err)
+			}
+
+			if conf.Memory.TLB.LookupTime > 0 {
+				tlb.LookupTime = conf.Memory.TLB.LookupTime
+			}
+
+			s.TLB = tlb
+		}
+	}
+
+	// Mailboxes shared by every CPU
+	for i := 0; i < conf.IPC.Mailboxes; i++ {
+		k.AddMailbox(conf.IPC.MailboxSize)
+	}
+
+	// Pipes hold as many values as the config says
+	k.PipeSize = conf.IPC.PipeSize
+
+	// IO devices shared by every CPU
+	for i := 0; i < conf.IO.Devices; i++ {
+		k.AddDevice(conf.IO.ClockSpeed)
+	}
+
+	// Page faults block the process while the page is swapped in
+	if conf.Memory.FaultLatency > 0 {
+		k.AddPager(conf.Memory.FaultLatency)
+	}
+
+	// Suspend processes when their working sets don't fit in memory
+	if conf.Memory.Thrashing != nil {
+		mem.Window = conf.Memory.Thrashing.Window
+		k.AddPFF(time.Duration(conf.Memory.Thrashing.Interval)*time.Millisecond, conf.Memory.Thrashing.Lower, conf.Memory.Thrashing.Upper)
+	}
+
+	// Children of an exiting process are terminated or handed to init
+	k.Cascade = conf.Sched.Cascade
+
+	// Look for processes that are blocked for good
+	if conf.Sched.Deadlock != nil && conf.Sched.Deadlock.Mode == "detect" {
+		k.AddDetector(time.Duration(conf.Sched.Deadlock.Interval)*time.Millisecond, conf.Sched.Deadlock.Recovery)
+	}
+
+	// Keep processes from getting into a deadlock in the first place
+	k.Avoidance = conf.Sched.Deadlock != nil && conf.Sched.Deadlock.Mode == "avoid"
+
+	// Run the schedulers
+	go k.Run()
 
 	// Initialize the TUI
 	if err := ui.Init(); err != nil {
@@ -45,7 +175,7 @@ func main() {
 	defer ui.Close()
 
 	// Point the widgets to the scheduler
-	tui.InitWidgets(s1)
+	tui.InitWidgets(k)
 
 	// Render initial state to the terminal
 	tui.RenderTUI()
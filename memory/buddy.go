@@ -0,0 +1,194 @@
+package memory
+
+import "sort"
+
+// kernelFrame : stands in physical memory for frames the kernel holds, so user pages can't be put there
+var kernelFrame = &Page{PageID: -1, ProcID: -1, frame: -1, slot: -1}
+
+// Buddy : binary buddy allocator handing out blocks of 2^order contiguous frames for kernel memory
+//
+// Every frame starts out in a free block, whether or not a user page is in it.
+// Taking a block for the kernel swaps out the user pages in it, and freeing one
+// gives its frames back to user pages and merges it with its buddy when that's
+// free too.
+type Buddy struct {
+	MaxOrder int // Order of the largest block
+	Reserve  int // The kernel always leaves more frames than this for user pages
+
+	free      [][]int     // First frame of each free block, sorted, indexed by order
+	allocated map[int]int // Order of each block the kernel holds by its first frame
+	frames    int         // Frames the kernel holds
+	evictions int         // User pages swapped out to make room for the kernel
+	memory    *Memory     // Memory the frames are in, its lock guards the allocator too
+}
+
+// AddBuddy : allocate kernel memory out of physical memory with a buddy allocator, leaving more than reserve frames for user pages
+func (m *Memory) AddBuddy(reserve int) *Buddy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	frames := len(m.PhysicalMemory)
+
+	maxOrder := 0
+	for 1<<(maxOrder+1) <= frames {
+		maxOrder++
+	}
+
+	b := &Buddy{
+		MaxOrder:  maxOrder,
+		Reserve:   reserve,
+		free:      make([][]int, maxOrder+1),
+		allocated: make(map[int]int),
+		memory:    m,
+	}
+
+	// Cover memory with the largest blocks that fit where they start
+	for frame := 0; frame < frames; {
+		order := maxOrder
+		for frame%(1<<order) != 0 || frame+1<<order > frames {
+			order--
+		}
+
+		b.free[order] = append(b.free[order], frame)
+		frame += 1 << order
+	}
+
+	m.Buddy = b
+
+	return b
+}
+
+// Order : smallest order of a block holding that many bytes
+func (b *Buddy) Order(bytes int) int {
+	order := 0
+	for (1<<order)*b.memory.PageSize < bytes {
+		order++
+	}
+
+	return order
+}
+
+// alloc : first frame of a block of 2^order frames for the kernel, ErrNoMemory if
+// there's no free block that big or it wouldn't leave more than Reserve frames for user pages, the lock must be held
+func (b *Buddy) alloc(order int) (int, error) {
+	m := b.memory
+
+	if order > b.MaxOrder || len(m.PhysicalMemory)-b.frames-1<<order <= b.Reserve {
+		return -1, ErrNoMemory
+	}
+
+	// Smallest free block big enough
+	o := order
+	for o <= b.MaxOrder && len(b.free[o]) == 0 {
+		o++
+	}
+
+	if o > b.MaxOrder {
+		return -1, ErrNoMemory
+	}
+
+	frame := b.free[o][0]
+	b.free[o] = b.free[o][1:]
+
+	// Split it in halves, keeping the first and freeing its buddy
+	for o > order {
+		o--
+		b.insert(o, frame+1<<o)
+	}
+
+	b.allocated[frame] = order
+	b.frames += 1 << order
+
+	// User pages in the block have to go
+	for i := frame; i < frame+1<<order; i++ {
+		if page := m.PhysicalMemory[i]; page != nil {
+			m.evict(page)
+			b.evictions++
+		}
+
+		m.PhysicalMemory[i] = kernelFrame
+	}
+
+	return frame, nil
+}
+
+// release : give a block back to user pages, merging it with its buddy while that's free, the lock must be held
+func (b *Buddy) release(frame int) {
+	m := b.memory
+
+	order, ok := b.allocated[frame]
+	if !ok {
+		return
+	}
+
+	delete(b.allocated, frame)
+	b.frames -= 1 << order
+
+	for i := frame; i < frame+1<<order; i++ {
+		m.PhysicalMemory[i] = nil
+	}
+
+	for order < b.MaxOrder {
+		buddy := frame ^ 1<<order
+
+		i := sort.SearchInts(b.free[order], buddy)
+		if i == len(b.free[order]) || b.free[order][i] != buddy {
+			break
+		}
+
+		b.free[order] = append(b.free[order][:i], b.free[order][i+1:]...)
+
+		if buddy < frame {
+			frame = buddy
+		}
+		order++
+	}
+
+	b.insert(order, frame)
+}
+
+// insert : add a free block to the list of its order, keeping it sorted, the lock must be held
+func (b *Buddy) insert(order int, frame int) {
+	i := sort.SearchInts(b.free[order], frame)
+
+	b.free[order] = append(b.free[order], 0)
+	copy(b.free[order][i+1:], b.free[order][i:])
+	b.free[order][i] = frame
+}
+
+// Alloc : first frame of a block of 2^order frames for the kernel
+func (b *Buddy) Alloc(order int) (int, error) {
+	b.memory.mu.Lock()
+	defer b.memory.mu.Unlock()
+
+	return b.alloc(order)
+}
+
+// Free : give back a block the kernel got from Alloc
+func (b *Buddy) Free(frame int) {
+	b.memory.mu.Lock()
+	defer b.memory.mu.Unlock()
+
+	b.release(frame)
+}
+
+// FreeLists : number of free blocks of each order
+func (b *Buddy) FreeLists() []int {
+	b.memory.mu.Lock()
+	defer b.memory.mu.Unlock()
+
+	counts := make([]int, len(b.free))
+	for order, blocks := range b.free {
+		counts[order] = len(blocks)
+	}
+
+	return counts
+}
+
+// Stats : frames the kernel holds and user pages swapped out to make room for it
+func (b *Buddy) Stats() (int, int) {
+	b.memory.mu.Lock()
+	defer b.memory.mu.Unlock()
+
+	return b.frames, b.evictions
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuddySplitsAndCoalesces(t *testing.T) {
+	m := InitMemory(16, 256)
+	b := m.AddBuddy(0)
+
+	if b.MaxOrder != 4 || !reflect.DeepEqual(b.FreeLists(), []int{0, 0, 0, 0, 1}) {
+		t.Fatalf("16 frames should be one free block. order=%d lists=%v", b.MaxOrder, b.FreeLists())
+	}
+
+	// One frame splits the block all the way down, leaving a buddy of each order
+	first, err := b.Alloc(0)
+	if err != nil || first != 0 {
+		t.Fatalf("first frame should be handed out. frame=%d err=%v", first, err)
+	}
+
+	if lists := b.FreeLists(); !reflect.DeepEqual(lists, []int{1, 1, 1, 1, 0}) {
+		t.Errorf("every split should leave a buddy. got=%v", lists)
+	}
+
+	second, _ := b.Alloc(1)
+	if second != 2 {
+		t.Errorf("order 1 block should come from the free one at frame 2. got=%d", second)
+	}
+
+	if m.FreeFrames() != 13 {
+		t.Errorf("user pages should lose the kernel's frames. got=%d free", m.FreeFrames())
+	}
+
+	b.Free(first)
+	b.Free(second)
+
+	if lists := b.FreeLists(); !reflect.DeepEqual(lists, []int{0, 0, 0, 0, 1}) {
+		t.Errorf("freed blocks should merge back into one. got=%v", lists)
+	}
+
+	// The kernel can't take every frame
+	if _, err := b.Alloc(4); err != ErrNoMemory {
+		t.Errorf("kernel shouldn't get all of memory. got=%v", err)
+	}
+}
+
+func TestBuddyLeavesReserveForUserPages(t *testing.T) {
+	m := InitMemory(16, 256)
+	b := m.AddBuddy(4)
+
+	if _, err := b.Alloc(3); err != nil {
+		t.Fatalf("half of memory should leave enough for user pages. got=%v", err)
+	}
+
+	// Another 4 frames would leave only the reserve
+	if _, err := b.Alloc(2); err != ErrNoMemory {
+		t.Errorf("kernel shouldn't eat into the reserve. got=%v", err)
+	}
+
+	if _, err := b.Alloc(1); err != nil || m.FreeFrames() != 6 {
+		t.Errorf("2 more frames still leave more than the reserve. free=%d err=%v", m.FreeFrames(), err)
+	}
+}
+
+func TestBuddyEvictsUserPages(t *testing.T) {
+	m := InitMemory(16, 64)
+	b := m.AddBuddy(0)
+
+	m.Add(32, 1)
+	m.PageIn(1, 0)
+	m.PageIn(1, 1)
+
+	if _, err := b.Alloc(1); err != nil {
+		t.Fatalf("kernel should get two frames. got=%v", err)
+	}
+
+	if frames, evictions := b.Stats(); frames != 2 || evictions != 2 {
+		t.Errorf("both user pages should be swapped out. frames=%d evictions=%d", frames, evictions)
+	}
+
+	// Only the frames left to users are used for faults
+	m.PageIn(1, 0)
+	m.PageIn(1, 1)
+
+	if physical, _ := m.Usage(); physical != 2 || m.Frames() != 2 {
+		t.Errorf("user pages should fit in the frames the kernel left. physical=%d frames=%d", physical, m.Frames())
+	}
+}
+
+func TestSlabCache(t *testing.T) {
+	m := InitMemory(16, 512)
+	m.AddBuddy(0)
+
+	c, err := m.AddCache("pcb", 24)
+	if err != nil {
+		t.Fatalf("cache should be made. got=%v", err)
+	}
+
+	// 4 objects of 24 bytes need 96 bytes, so a slab is 8 frames holding 5
+	if c.Order != 3 || c.PerSlab != 5 {
+		t.Fatalf("wrong slab size. order=%d objects=%d", c.Order, c.PerSlab)
+	}
+
+	for i := 0; i < 6; i++ {
+		if _, err := c.Alloc(); err != nil {
+			t.Fatalf("object %d should be allocated. got=%v", i, err)
+		}
+	}
+
+	if used, capacity, _ := c.Stats(); used != 6 || capacity != 10 {
+		t.Errorf("sixth object should take a second slab. used=%d capacity=%d", used, capacity)
+	}
+
+	// Fill the second slab and a third, a fourth would leave nothing for user pages
+	for i := 0; i < 9; i++ {
+		c.Alloc()
+	}
+
+	if _, err := c.Alloc(); err != ErrNoMemory {
+		t.Errorf("fourth slab shouldn't fit. got=%v", err)
+	}
+
+	// Emptying the second slab, at frame 8, gives its frames back
+	for i := 0; i < c.PerSlab; i++ {
+		c.Free(8*m.PageSize + i*c.Size)
+	}
+
+	if frames, _ := m.Buddy.Stats(); frames != 16 {
+		t.Errorf("empty slab should go back to the buddy allocator. got=%d kernel frames", frames)
+	}
+
+	if _, _, failures := c.Stats(); failures != 1 {
+		t.Errorf("failed allocation should be counted. got=%d", failures)
+	}
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"hash/crc32"
+	"sort"
+)
+
+// checksum : checksum of a page's contents, as the page of zeros it's padded to in the swap file
+func (m *Memory) checksum(contents []byte) uint32 {
+	page := make([]byte, m.PageSize)
+	copy(page, contents)
+
+	return crc32.ChecksumIEEE(page)
+}
+
+// zeroed : whether contents are all zeros, a page that's never been written
+func zeroed(contents []byte) bool {
+	for _, b := range contents {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// locate : page table entry of a virtual address and the offset into its page, nil without one, the lock must be held
+func (m *Memory) locate(pid int, vaddr int) (*PTE, int) {
+	linear := vaddr
+	if m.Segmented && vaddr < SharedBase {
+		var err error
+		if linear, err = m.segment(pid, vaddr, ProtAll); err != nil {
+			return nil, 0
+		}
+	}
+
+	if linear < 0 {
+		return nil, 0
+	}
+
+	return m.entry(pid, linear/m.PageSize), linear % m.PageSize
+}
+
+// Load : byte at a virtual address of a process, ErrPageFault if its page isn't in physical memory
+func (m *Memory) Load(pid int, vaddr int) (byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Regions are always in physical memory
+	if m.Contiguous != nil {
+		addr, err := m.translate(pid, vaddr, ProtAll)
+		if err != nil {
+			return 0, err
+		}
+
+		return m.Contiguous.ram[addr], nil
+	}
+
+	entry, offset := m.locate(pid, vaddr)
+	if entry == nil {
+		return 0, ErrBadAddress
+	}
+
+	if !entry.Valid {
+		return 0, ErrPageFault
+	}
+
+	return entry.page.contents[offset], nil
+}
+
+// Store : write a byte to a virtual address of a process, ErrPageFault if its page isn't in physical memory
+//
+// It doesn't copy a page shared copy-on-write, Write has to do that first.
+func (m *Memory) Store(pid int, vaddr int, value byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Contiguous != nil {
+		addr, err := m.translate(pid, vaddr, ProtAll)
+		if err != nil {
+			return err
+		}
+
+		m.Contiguous.ram[addr] = value
+		return nil
+	}
+
+	entry, offset := m.locate(pid, vaddr)
+	if entry == nil {
+		return ErrBadAddress
+	}
+
+	if !entry.Valid {
+		return ErrPageFault
+	}
+
+	entry.page.contents[offset] = value
+	entry.Dirty = true
+
+	return nil
+}
+
+// Check : consistency check of every page outside of physical memory against the checksum it left with,
+// returns the IDs of pages whose contents changed since
+func (m *Memory) Check() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bad := []int{}
+	for id, p := range m.pages {
+		if p.frame >= 0 {
+			continue
+		}
+
+		contents := p.contents
+		if contents == nil && p.slot >= 0 && m.Swap != nil {
+			contents, _ = m.Swap.peek(p.slot)
+		}
+
+		if m.checksum(contents) != p.checksum {
+			bad = append(bad, id)
+		}
+	}
+
+	sort.Ints(bad)
+
+	return bad
+}
+
+// Corrupted : IDs of pages whose contents came back into physical memory different from how they left
+func (m *Memory) Corrupted() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]int{}, m.corrupted...)
+}
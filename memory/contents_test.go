@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newSwapped makes memory with room for two 4 byte pages and a swap file, process 1 has four pages
+func newSwapped(t *testing.T) (*Memory, *Swap) {
+	dir, err := ioutil.TempDir("", "swap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := InitMemory(4, 8)
+	m.Add(16, 1)
+
+	sw, err := m.AddSwap(filepath.Join(dir, "swap"))
+	if err != nil {
+		t.Fatalf("swap: %v", err)
+	}
+
+	return m, sw
+}
+
+func TestContentsSurviveEviction(t *testing.T) {
+	for _, swapped := range []bool{false, true} {
+		m := InitMemory(4, 8)
+		m.Add(16, 1)
+
+		if swapped {
+			mem, sw := newSwapped(t)
+			defer os.RemoveAll(filepath.Dir(sw.Path))
+			defer sw.Close()
+
+			m = mem
+		}
+
+		// Two frames for four pages, every page gets evicted
+		for vpn := 0; vpn < 4; vpn++ {
+			touch(m, 1, vpn)
+			if err := m.Store(1, vpn*4+1, byte(10+vpn)); err != nil {
+				t.Fatalf("store to page %d should work. got=%v", vpn, err)
+			}
+		}
+
+		if _, err := m.Load(1, 1); err != ErrPageFault {
+			t.Errorf("page 0 should have been evicted. got=%v", err)
+		}
+
+		for vpn := 0; vpn < 4; vpn++ {
+			touch(m, 1, vpn)
+			if value, err := m.Load(1, vpn*4+1); err != nil || value != byte(10+vpn) {
+				t.Errorf("page %d lost its contents. swap=%t want=%d, got=%d err=%v", vpn, swapped, 10+vpn, value, err)
+			}
+		}
+
+		if bad := m.Check(); len(bad) != 0 || len(m.Corrupted()) != 0 {
+			t.Errorf("no page should be corrupted. swap=%t got=%v", swapped, bad)
+		}
+	}
+}
+
+func TestCheckCatchesAlteredSwap(t *testing.T) {
+	m, sw := newSwapped(t)
+	defer os.RemoveAll(filepath.Dir(sw.Path))
+	defer sw.Close()
+
+	touch(m, 1, 0)
+	m.Store(1, 2, 7)
+	touch(m, 1, 1)
+	touch(m, 1, 2)
+
+	page := m.tables[1][0].page
+	if page.slot < 0 {
+		t.Fatalf("page 0 should be in the swap file")
+	}
+
+	// Something else scribbles over its slot
+	sw.file.WriteAt([]byte{9}, int64(page.slot*sw.size))
+
+	if bad := m.Check(); len(bad) != 1 || bad[0] != page.PageID {
+		t.Errorf("check should catch page %d. got=%v", page.PageID, bad)
+	}
+
+	touch(m, 1, 0)
+
+	if corrupted := m.Corrupted(); len(corrupted) != 1 || corrupted[0] != page.PageID {
+		t.Errorf("page %d should be caught coming back in. got=%v", page.PageID, corrupted)
+	}
+}
+
+func TestContiguousContentsSurviveCompaction(t *testing.T) {
+	m := InitMemory(16, 256)
+	m.AddContiguous("first", false)
+
+	m.Place(1, 64)
+	m.Place(2, 32)
+	m.Store(2, 5, 42)
+	m.RemovePages(1)
+
+	m.Compact()
+
+	if base, _ := m.Translate(2, 0, ProtRead); base != 0 {
+		t.Fatalf("process 2 should be moved to the start of memory. got=%d", base)
+	}
+
+	if value, err := m.Load(2, 5); err != nil || value != 42 {
+		t.Errorf("contents should move with the region. got=%d err=%v", value, err)
+	}
+
+	if err := m.Store(2, 32, 1); err != ErrBadAddress {
+		t.Errorf("store past the region should fail. got=%v", err)
+	}
+}
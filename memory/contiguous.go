@@ -0,0 +1,364 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Fit strategies for placing a process in a hole
+var Fits = []string{"first", "best", "worst", "next"}
+
+// Hole : range of physical memory, free or given to a process
+type Hole struct {
+	Base int // First byte
+	Size int // Number of bytes
+}
+
+// Contiguous : allocator that gives each process one contiguous region of physical memory instead of pages
+//
+// Regions are whole pages long and translation adds the region's base to the
+// virtual address after checking it against the region's limit, so nothing is
+// ever paged in or out. Compaction slides every region down to the start of
+// memory so the holes between them become one, contents and all. A forked
+// child can't share its parent's region, it starts with a copy of it instead.
+type Contiguous struct {
+	Fit        string // Hole to place a process in: first || best || worst || next
+	Compaction bool   // Compact memory when no hole fits but there's enough free memory in total
+
+	holes       []Hole           // Free memory, sorted by base and never next to each other
+	regions     map[int][]Hole   // Memory of each process, one region for each of its segments with segmentation
+	next        int              // Base of the hole the last search stopped at, for next fit
+	compactions int              // Compaction passes
+	moved       int              // Regions relocated by compaction
+	ram         []byte           // Contents of physical memory
+	inherited   map[int][][]byte // Contents of each region of a forked child's parent, copied in once the child is placed
+	memory      *Memory          // Memory the allocator places processes in, its lock guards the allocator too
+}
+
+// AddContiguous : allocate physical memory contiguously instead of paging it
+func (m *Memory) AddContiguous(fit string, compaction bool) (*Contiguous, error) {
+
+	valid := false
+	for _, name := range Fits {
+		valid = valid || name == fit
+	}
+
+	if !valid {
+		return nil, fmt.Errorf("unknown fit strategy %q", fit)
+	}
+
+	c := &Contiguous{
+		Fit:        fit,
+		Compaction: compaction,
+		holes:      []Hole{{Base: 0, Size: m.TotalRam}},
+		regions:    make(map[int][]Hole),
+		ram:        make([]byte, m.TotalRam),
+		inherited:  make(map[int][][]byte),
+		memory:     m,
+	}
+
+	m.mu.Lock()
+	m.Contiguous = c
+	m.mu.Unlock()
+
+	return c, nil
+}
+
+// size : bytes a process with that memory requirement gets, rounded up to whole pages
+func (c *Contiguous) size(requirement int) int {
+	pageSize := c.memory.PageSize
+
+	return int(math.Ceil(float64(requirement)/float64(pageSize))) * pageSize
+}
+
+// find : index of the hole the fit strategy places size bytes in, -1 if none is big enough
+func (c *Contiguous) find(size int) int {
+	found := -1
+
+	switch c.Fit {
+	case "first":
+		for i, h := range c.holes {
+			if h.Size >= size {
+				return i
+			}
+		}
+
+	case "best":
+		for i, h := range c.holes {
+			if h.Size >= size && (found == -1 || h.Size < c.holes[found].Size) {
+				found = i
+			}
+		}
+
+	case "worst":
+		for i, h := range c.holes {
+			if h.Size >= size && (found == -1 || h.Size > c.holes[found].Size) {
+				found = i
+			}
+		}
+
+	case "next":
+		// Pick up at the hole where the last search stopped and wrap around
+		start := sort.Search(len(c.holes), func(i int) bool {
+			return c.holes[i].Base+c.holes[i].Size > c.next
+		})
+
+		for j := 0; j < len(c.holes); j++ {
+			i := (start + j) % len(c.holes)
+			if c.holes[i].Size >= size {
+				return i
+			}
+		}
+	}
+
+	return found
+}
+
+// Place : give a process a region of memory big enough for its requirement, return its number of pages
+//
+// With segmentation every segment of the process gets a region of its own
+// instead. Memory is compacted first if no hole fits, compaction is on and
+// there's enough free memory in total. Without room for the process the error
+// is ErrNoHole.
+func (m *Memory) Place(pid int, requirement int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.Contiguous
+
+	sizes := []int{c.size(requirement)}
+	if m.Segmented {
+		segments := m.segmentTable(pid, requirement)
+
+		sizes = make([]int, len(segments))
+		for i, seg := range segments {
+			sizes[i] = c.size(seg.Limit)
+		}
+	}
+
+	total := 0
+	for _, size := range sizes {
+		total += size
+	}
+
+	if total == 0 {
+		return 0, nil
+	}
+
+	if !c.place(pid, sizes) {
+		if !c.Compaction || c.free() < total {
+			return 0, ErrNoHole
+		}
+
+		c.compact()
+
+		if !c.place(pid, sizes) {
+			return 0, ErrNoHole
+		}
+	}
+
+	return total / m.PageSize, nil
+}
+
+// place : carve a region of each size out of the holes for a process, all of them or none, the lock must be held
+func (c *Contiguous) place(pid int, sizes []int) bool {
+	for i, size := range sizes {
+
+		// Empty segments still get a region so they line up with the segment table
+		if size == 0 {
+			c.regions[pid] = append(c.regions[pid], Hole{})
+			continue
+		}
+
+		h := c.find(size)
+		if h == -1 {
+			c.release(pid)
+			return false
+		}
+
+		// The region takes the start of the hole and the rest stays free
+		hole := c.holes[h]
+		c.regions[pid] = append(c.regions[pid], Hole{Base: hole.Base, Size: size})
+		c.next = hole.Base + size
+
+		// Fresh memory starts out zeroed like a fresh page, unless it's a copy of a parent's region
+		for b := hole.Base; b < hole.Base+size; b++ {
+			c.ram[b] = 0
+		}
+
+		if contents := c.inherited[pid]; i < len(contents) {
+			copy(c.ram[hole.Base:hole.Base+size], contents[i])
+		}
+
+		if hole.Size == size {
+			c.holes = append(c.holes[:h], c.holes[h+1:]...)
+		} else {
+			c.holes[h] = Hole{Base: hole.Base + size, Size: hole.Size - size}
+		}
+	}
+
+	delete(c.inherited, pid)
+
+	return true
+}
+
+// inherit : copy the contents of a parent's regions for its forked child to start with, the lock must be held
+//
+// A parent that hasn't been placed yet passes on what it inherited itself.
+func (c *Contiguous) inherit(parent int, child int) {
+	regions, ok := c.regions[parent]
+	if !ok {
+		if contents, ok := c.inherited[parent]; ok {
+			c.inherited[child] = contents
+		}
+
+		return
+	}
+
+	contents := make([][]byte, len(regions))
+	for i, region := range regions {
+		contents[i] = append([]byte{}, c.ram[region.Base:region.Base+region.Size]...)
+	}
+
+	c.inherited[child] = contents
+}
+
+// release : give the regions of a process back, the lock must be held
+func (c *Contiguous) release(pid int) {
+	for _, region := range c.regions[pid] {
+		if region.Size > 0 {
+			c.reclaim(region)
+		}
+	}
+
+	delete(c.regions, pid)
+}
+
+// reclaim : put a region back in the hole list, merging it with the holes next to it, the lock must be held
+func (c *Contiguous) reclaim(region Hole) {
+	i := sort.Search(len(c.holes), func(i int) bool {
+		return c.holes[i].Base > region.Base
+	})
+
+	c.holes = append(c.holes, Hole{})
+	copy(c.holes[i+1:], c.holes[i:])
+	c.holes[i] = region
+
+	// Merge with the hole after it, then the one before it
+	if i+1 < len(c.holes) && region.Base+region.Size == c.holes[i+1].Base {
+		c.holes[i].Size += c.holes[i+1].Size
+		c.holes = append(c.holes[:i+1], c.holes[i+2:]...)
+	}
+
+	if i > 0 && c.holes[i-1].Base+c.holes[i-1].Size == region.Base {
+		c.holes[i-1].Size += c.holes[i].Size
+		c.holes = append(c.holes[:i], c.holes[i+1:]...)
+	}
+}
+
+// translate : physical address of a virtual address of a process in its region, the lock must be held
+func (c *Contiguous) translate(pid int, vaddr int) (int, error) {
+	regions := c.regions[pid]
+	if len(regions) == 0 || vaddr < 0 || vaddr >= regions[0].Size {
+		return -1, ErrBadAddress
+	}
+
+	return regions[0].Base + vaddr, nil
+}
+
+// free : bytes in every hole, the lock must be held
+func (c *Contiguous) free() int {
+	free := 0
+	for _, h := range c.holes {
+		free += h.Size
+	}
+
+	return free
+}
+
+// compact : slide every region down to the start of memory leaving one hole at the end, the lock must be held
+func (c *Contiguous) compact() {
+	type owned struct {
+		pid, index int
+	}
+
+	regions := []owned{}
+	for pid, held := range c.regions {
+		for i, region := range held {
+			if region.Size > 0 {
+				regions = append(regions, owned{pid, i})
+			}
+		}
+	}
+
+	sort.Slice(regions, func(i, j int) bool {
+		return c.regions[regions[i].pid][regions[i].index].Base < c.regions[regions[j].pid][regions[j].index].Base
+	})
+
+	base := 0
+	for _, r := range regions {
+		region := &c.regions[r.pid][r.index]
+
+		// Relocating only changes the base, addresses are translated on every access
+		if region.Base != base {
+			copy(c.ram[base:], c.ram[region.Base:region.Base+region.Size])
+			region.Base = base
+			c.moved++
+		}
+
+		base += region.Size
+	}
+
+	c.holes = []Hole{}
+	if base < c.memory.TotalRam {
+		c.holes = append(c.holes, Hole{Base: base, Size: c.memory.TotalRam - base})
+	}
+
+	c.next = 0
+	c.compactions++
+}
+
+// Compact : relocate every process to the start of memory so the free memory is one hole
+func (m *Memory) Compact() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Contiguous.compact()
+}
+
+// Holes : copy of the free-hole list, sorted by base
+func (m *Memory) Holes() []Hole {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Hole{}, m.Contiguous.holes...)
+}
+
+// Fragmentation : external fragmentation, the fraction of free memory outside of the largest hole
+func (m *Memory) Fragmentation() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	free, largest := 0, 0
+	for _, h := range m.Contiguous.holes {
+		free += h.Size
+		if h.Size > largest {
+			largest = h.Size
+		}
+	}
+
+	if free == 0 {
+		return 0
+	}
+
+	return 1 - float64(largest)/float64(free)
+}
+
+// Compactions : compaction passes and regions relocated by them
+func (m *Memory) Compactions() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.Contiguous.compactions, m.Contiguous.moved
+}
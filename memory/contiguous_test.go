@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"math"
+	"testing"
+)
+
+// newContiguous makes contiguous memory with processes 1 and 3 gone, leaving holes of 64, 96 and 16 bytes
+func newContiguous(t *testing.T, fit string, compaction bool) *Memory {
+	m := InitMemory(16, 256)
+	if _, err := m.AddContiguous(fit, compaction); err != nil {
+		t.Fatalf("%s fit should be allowed. got=%v", fit, err)
+	}
+
+	for pid, size := range []int{64, 32, 96, 32, 16} {
+		if _, err := m.Place(pid+1, size); err != nil {
+			t.Fatalf("process %d should fit. got=%v", pid+1, err)
+		}
+	}
+
+	m.RemovePages(1)
+	m.RemovePages(3)
+
+	return m
+}
+
+func TestFitStrategies(t *testing.T) {
+	tests := []struct {
+		fit   string
+		bases []int
+	}{
+		{"first", []int{0, 16}},
+		{"best", []int{240, 0}},
+		{"worst", []int{96, 112}},
+	}
+
+	for _, tt := range tests {
+		m := newContiguous(t, tt.fit, false)
+
+		for i, size := range []int{16, 48} {
+			pid := 10 + i
+			if _, err := m.Place(pid, size); err != nil {
+				t.Fatalf("%s fit should find a hole for %d bytes. got=%v", tt.fit, size, err)
+			}
+
+			if base, _ := m.Translate(pid, 0, ProtRead); base != tt.bases[i] {
+				t.Errorf("%s fit placed %d bytes at the wrong base. want=%d, got=%d", tt.fit, size, tt.bases[i], base)
+			}
+		}
+	}
+}
+
+func TestNextFitPicksUpWhereItStopped(t *testing.T) {
+	m := InitMemory(16, 256)
+	m.AddContiguous("next", false)
+
+	m.Place(1, 64)
+	m.Place(2, 64)
+	m.RemovePages(1)
+
+	m.Place(3, 32)
+	if base, _ := m.Translate(3, 0, ProtRead); base != 128 {
+		t.Errorf("next fit should carry on after process 2. got=%d", base)
+	}
+}
+
+func TestReleaseMergesHoles(t *testing.T) {
+	m := newContiguous(t, "first", false)
+
+	// Only the 96 byte hole of the 176 free bytes can be used for something big
+	if frag := m.Fragmentation(); math.Abs(frag-80.0/176) > 1e-9 {
+		t.Errorf("wrong external fragmentation. got=%f", frag)
+	}
+
+	m.RemovePages(2)
+
+	holes := m.Holes()
+	if len(holes) != 2 || holes[0] != (Hole{Base: 0, Size: 192}) {
+		t.Errorf("freed region should merge with the holes on both sides. got=%v", holes)
+	}
+}
+
+func TestCompactionRelocatesProcesses(t *testing.T) {
+	for _, compaction := range []bool{false, true} {
+		m := newContiguous(t, "first", compaction)
+
+		// 160 bytes are free, but not in one hole
+		_, err := m.Place(6, 160)
+		if !compaction {
+			if err != ErrNoHole {
+				t.Errorf("no hole should fit without compaction. got=%v", err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("compaction should make room. got=%v", err)
+		}
+
+		if passes, moved := m.Compactions(); passes != 1 || moved != 3 {
+			t.Errorf("every process should be moved down once. passes=%d moved=%d", passes, moved)
+		}
+
+		// Process 2 was at 64 and its addresses follow it
+		if addr, _ := m.Translate(2, 8, ProtRead); addr != 8 {
+			t.Errorf("process 2 should be relocated to the start. got=%d", addr)
+		}
+
+		if _, err := m.Translate(6, 160, ProtRead); err != ErrBadAddress {
+			t.Errorf("addresses past the region should be out of range. got=%v", err)
+		}
+	}
+}
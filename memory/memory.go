@@ -1,13 +1,48 @@
 package memory
 
 import (
+	"errors"
+	"log"
 	"math"
+	"sync"
+)
+
+const (
+
+	// Page protection bits
+
+	// ProtRead : the page can be read
+	ProtRead = 1 << iota
 
-	"github.com/hashicorp/golang-lru"
+	// ProtWrite : the page can be written
+	ProtWrite
+
+	// ProtExec : instructions can be fetched from the page
+	ProtExec
+
+	// ProtAll : every kind of access
+	ProtAll = ProtRead | ProtWrite | ProtExec
 )
 
 var (
-	pageNum = 0
+
+	// ErrPageFault : the page is valid but not in physical memory
+	ErrPageFault = errors.New("page fault")
+
+	// ErrBadAddress : the address is outside of the process's pages
+	ErrBadAddress = errors.New("address out of range")
+
+	// ErrProtection : the page doesn't allow that kind of access
+	ErrProtection = errors.New("protection fault")
+
+	// ErrSegmentFault : the address is past the end of its segment or in a segment the process doesn't have
+	ErrSegmentFault = errors.New("segment fault")
+
+	// ErrNoMemory : the kernel can't get any more memory
+	ErrNoMemory = errors.New("out of kernel memory")
+
+	// ErrNoHole : no hole is big enough for the process
+	ErrNoHole = errors.New("no hole fits")
 )
 
 type Memory struct {
@@ -17,173 +52,686 @@ type Memory struct {
 	// TotalRam : Total amount of physical memory in the simulator in Mb as a power of 2
 	TotalRam int
 
-	// PageTable : map page id to index of frame
-	PageTable map[int]int
+	// PhysicalMemory : Memory in RAM, one entry per frame, nil for a free frame and a placeholder page for frames the kernel holds
+	PhysicalMemory []*Page
+
+	// Policy : picks the page to replace when physical memory is full
+	Policy ReplacementPolicy
 
-	// VirtualMemory : pages in secondary memory
-	VirtualMemory []*Page
+	// Contiguous : allocator giving each process one region of physical memory, nil pages memory
+	Contiguous *Contiguous
 
-	// PhysicalMemory : Memory in RAM
-	PhysicalMemory []*Page
+	// Buddy : allocator for kernel memory taking frames away from user pages, nil if the kernel doesn't use any
+	Buddy *Buddy
+
+	// Segmented : addresses pick a segment of the process, placed in holes with Contiguous and paged without
+	Segmented bool
+
+	// Swap : where pages that leave physical memory are written, nil keeps them in the simulator's memory
+	Swap *Swap
+
+	// Trace : file every page reference is written to, nil doesn't keep a trace
+	Trace *Trace
+
+	// Scope : GlobalScope or LocalScope, which pages a faulting process can replace
+	Scope string
+
+	// AccessTime : nanoseconds for a memory access, for the effective access time
+	AccessTime float64
+
+	// Window : working set window, in ticks of simulated time
+	Window int
+
+	// caches : slab caches of kernel objects
+	caches []*Cache
+
+	// tlbs : TLBs in front of memory, entries are shot down when the page table changes
+	tlbs []*TLB
+
+	// tables : page table of each process, indexed by virtual page number
+	tables map[int][]*PTE
+
+	// segments : segment table of each process
+	segments map[int][]Segment
+
+	// shared : shared memory segments by key
+	shared map[int]*sharedSegment
 
-	// Cache : Cache of pages
-	Cache *lru.ARCCache
+	// attached : page table entries of the shared segments each process attached, by key
+	attached map[int]map[int][]*PTE
+
+	// pages : every page in either memory by ID
+	pages map[int]*Page
+
+	// nextPage : ID for the next page created
+	nextPage int
+
+	// cowFaults : writes to a shared page that had to copy it first
+	cowFaults int
+
+	// faults : translations that found the page outside of physical memory
+	faults int
+
+	// corrupted : pages whose contents didn't match their checksum when they came back into physical memory
+	corrupted []int
+
+	// references : most recent pages translated, in order, for replaying
+	references []int
+
+	// clocks : memory references by each process, for its fault rate
+	clocks map[int]int
+
+	// ticks : simulated time, starting at 1 so a page used at 0 was never touched
+	ticks int
+
+	// procFaults : page faults of each process
+	procFaults map[int]int
+
+	// mu : guards the page tables and both memories since every CPU shares them
+	mu sync.Mutex
 }
 
 // Page : a page of memory
 type Page struct {
 	PageID   int    // ID of page
-	ProcID   int    // Process ID of the process using this page
-	contents []byte // Contents of the page of memory
+	ProcID   int    // Process ID of the process that created this page
+	refs     int    // Number of processes sharing the page, written to copy-on-write when above 1 unless it's shared memory
+	frame    int    // Frame the page is in, -1 when it's in virtual memory
+	slot     int    // Slot in the swap file holding the page, -1 if it was never written out
+	entries  []*PTE // Page table entries mapping the page
+	contents []byte // Contents of the page of memory, PageSize bytes, nil while only the swap file has them
+	checksum uint32 // Checksum of the contents when the page last left physical memory
+	shared   bool   // Page of a shared memory segment, every process writes to the same page
 }
 
-// InitMemory : create new memory unit
-func InitMemory(pageSize int, totalRam int, cacheSize int) *Memory {
+// PTE : page table entry mapping a virtual page of a process to a frame
+type PTE struct {
+	Frame      int  // Frame holding the page, only meaningful when Valid
+	Valid      bool // The page is in physical memory
+	Dirty      bool // The page was written since it was brought in
+	Referenced bool // The page was touched since it was brought in
+	Protection int  // Kinds of access allowed, ProtRead | ProtWrite | ProtExec
+	used       int  // Tick a process last touched the page at, 0 if it never did
+	page       *Page
+}
 
-	cache, _ := lru.NewARC(cacheSize)
+// InitMemory : create new memory unit
+func InitMemory(pageSize int, totalRam int) *Memory {
 
 	return &Memory{
 		PageSize:       pageSize,
 		TotalRam:       totalRam,
-		PageTable:      make(map[int]int),
-		VirtualMemory:  make([]*Page, 0),
-		PhysicalMemory: make([]*Page, 0, totalRam/pageSize),
-		Cache:          cache,
+		PhysicalMemory: make([]*Page, totalRam/pageSize),
+		Policy:         NewFIFO(),
+		Scope:          GlobalScope,
+		AccessTime:     DefaultAccessTime,
+		Window:         DefaultWindow,
+		clocks:         make(map[int]int),
+		ticks:          1,
+		procFaults:     make(map[int]int),
+		tables:         make(map[int][]*PTE),
+		segments:       make(map[int][]Segment),
+		shared:         make(map[int]*sharedSegment),
+		attached:       make(map[int]map[int][]*PTE),
+		pages:          make(map[int]*Page),
 	}
 }
 
-// GetPage : get a page of memory
-func (m *Memory) Get(pageNum int) *Page {
+// newPage makes a page for a process in virtual memory
+func (m *Memory) newPage(pid int, contents []byte) *Page {
+	m.nextPage++
+
+	p := &Page{
+		PageID:   m.nextPage,
+		ProcID:   pid,
+		refs:     1,
+		frame:    -1,
+		slot:     -1,
+		entries:  []*PTE{},
+		contents: contents,
+		checksum: m.checksum(contents),
+	}
+
+	m.pages[p.PageID] = p
+
+	return p
+}
+
+// Add : give a process enough pages for requirement at the end of its address space, return its number of pages
+func (m *Memory) Add(requirement int, pid int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Check if the page is in the cache
-	if result, ok := m.Cache.Get(pageNum); ok {
-		return result.(*Page)
+	// Each segment gets its own pages
+	if m.Segmented {
+		m.addSegments(pid, requirement)
+		return len(m.tables[pid])
 	}
 
-	// Check for page in PhysicalMemory
-	if val, ok := m.PageTable[pageNum]; ok {
-		return m.PhysicalMemory[val]
+	numOfPages := int(math.Ceil(float64(requirement) / float64(m.PageSize)))
+
+	for i := 0; i < numOfPages; i++ {
+		m.addPage(pid, ProtAll)
 	}
 
-	// Otherwise, look through virtual memory for the page
-	for i, page := range m.VirtualMemory {
-		if page.PageID == pageNum {
+	return len(m.tables[pid])
+}
+
+// addPage : new page at the end of a process's page table, the lock must be held
+func (m *Memory) addPage(pid int, protection int) {
+	p := m.newPage(pid, make([]byte, m.PageSize))
 
-			// Move the page to physical memory once found
-			m.moveToPhysicalMemory(page, i)
+	entry := &PTE{Protection: protection, page: p}
+	p.entries = append(p.entries, entry)
 
-			// Add page to the cache
-			m.Cache.Add(pageNum, page)
+	m.tables[pid] = append(m.tables[pid], entry)
+}
 
-			return page
+// Fork : share every page of the parent with the child copy-on-write, return the child's number of pages
+func (m *Memory) Fork(parent int, child int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.tables[parent] {
+		entry := &PTE{
+			Frame:      e.Frame,
+			Valid:      e.Valid,
+			Protection: e.Protection,
+			page:       e.page,
 		}
+
+		e.page.refs++
+		e.page.entries = append(e.page.entries, entry)
+
+		m.tables[child] = append(m.tables[child], entry)
+	}
+
+	if segments, ok := m.segments[parent]; ok {
+		m.segments[child] = append([]Segment{}, segments...)
+	}
+
+	// Shared memory stays attached in the child
+	for key := range m.attached[parent] {
+		m.attach(child, m.shared[key])
+	}
+
+	// Regions can't be shared, the child gets a copy of the parent's once it's placed
+	if m.Contiguous != nil {
+		m.Contiguous.inherit(parent, child)
 	}
 
-	// Page doesn't exist
-	return nil
+	return len(m.tables[child])
 }
 
-// AddPage : Add pages of memory to memory pool, return PageIDs
-func (m *Memory) Add(requirement int, pid int) []int {
+// PageTable : copy of the page table of a process
+func (m *Memory) PageTable(pid int) []PTE {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	var pageIds []int
+	table := make([]PTE, len(m.tables[pid]))
+	for i, e := range m.tables[pid] {
+		table[i] = *e
+	}
 
-	numOfPages := int(math.Ceil(float64(requirement) / float64(m.PageSize)))
+	return table
+}
 
-	for i := 0; i < numOfPages; i++ {
+// Protect : set the kinds of access allowed to a virtual page of a process
+func (m *Memory) Protect(pid int, vpn int, protection int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(pid, vpn)
+	if entry == nil {
+		return false
+	}
+
+	entry.Protection = protection
+	m.shootdown(entry)
+
+	return true
+}
+
+// entry : page table entry of a virtual page, nil if the process doesn't have it
+func (m *Memory) entry(pid int, vpn int) *PTE {
+	if vpn*m.PageSize >= SharedBase {
+		return m.sharedEntry(pid, vpn)
+	}
+
+	table := m.tables[pid]
+	if vpn < 0 || vpn >= len(table) {
+		return nil
+	}
+
+	return table[vpn]
+}
+
+// Write : write to a virtual page of a process, a page shared with another process gets copied first
+//
+// Returns whether it was a copy-on-write fault.
+func (m *Memory) Write(pid int, vpn int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(pid, vpn)
+	if entry == nil {
+		return false
+	}
 
-		pageNum++
+	page := entry.page
+	if page.refs <= 1 || page.shared {
+		entry.Dirty = true
+		return false
+	}
+
+	// Copy on write, the copy is the process's own
+	contents := m.contents(page)
+	copied := m.newPage(pid, append(make([]byte, 0, m.PageSize), contents...))
+
+	page.refs--
+	page.entries = removeEntry(page.entries, entry)
+	m.shootdown(entry)
+
+	entry.page = copied
+	copied.entries = append(copied.entries, entry)
 
-		p := &Page{
-			PageID:   pageNum,
-			ProcID:   pid,
-			contents: make([]byte, 0, 30),
+	// Copy is made in RAM where the original was being written
+	m.load(copied, pid)
+	entry.Dirty = true
+
+	m.cowFaults++
+
+	return true
+}
+
+// Translate : MMU, physical address of a virtual address of a process by walking its page table
+//
+// access is the kind of access, one of ProtRead, ProtWrite or ProtExec. ErrPageFault
+// means the page has to be brought in with PageIn before trying again. With
+// segmentation the address goes through the segment table first and an offset
+// past the end of its segment is ErrSegmentFault.
+func (m *Memory) Translate(pid int, vaddr int, access int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.translate(pid, vaddr, access)
+}
+
+// translate : Translate without locking, through the segment table, region or page table, the lock must be held
+func (m *Memory) translate(pid int, vaddr int, access int) (int, error) {
+	if m.Segmented && vaddr < SharedBase {
+		linear, err := m.segment(pid, vaddr, access)
+		if err != nil || m.Contiguous != nil {
+			return linear, err
 		}
 
-		pageIds = append(pageIds, pageNum)
+		return m.walk(pid, linear, access)
+	}
 
-		// Append new page to virtual memory
-		m.VirtualMemory = append(m.VirtualMemory, p)
+	if m.Contiguous != nil {
+		return m.Contiguous.translate(pid, vaddr)
 	}
 
-	// return pageIds for the process to keep track of
-	return pageIds
+	return m.walk(pid, vaddr, access)
 }
 
-// moveToPhysicalMemory puts pages into RAM and adds the entry to the PageTable
-func (m *Memory) moveToPhysicalMemory(p *Page, indexInVm int) {
+// walk : page table walk, the lock must be held
+func (m *Memory) walk(pid int, vaddr int, access int) (int, error) {
+	if vaddr < 0 {
+		return -1, ErrBadAddress
+	}
 
-	// Remove page from virtual memory
-	m.VirtualMemory = remove(m.VirtualMemory, indexInVm)
+	entry := m.entry(pid, vaddr/m.PageSize)
+	if entry == nil {
+		return -1, ErrBadAddress
+	}
 
-	// if there is an empty space, put page in empty space
-	if cap(m.PhysicalMemory)-len(m.PhysicalMemory) > 0 {
-		m.PhysicalMemory = append(m.PhysicalMemory, p)
+	if entry.Protection&access == 0 {
+		return -1, ErrProtection
+	}
+
+	if !entry.Valid {
+		m.record(entry.page.PageID)
+		m.stamp(pid, entry)
+
+		m.faults++
+		m.procFaults[pid]++
+
+		return -1, ErrPageFault
+	}
+
+	m.reference(pid, entry)
+
+	return entry.Frame*m.PageSize + vaddr%m.PageSize, nil
+}
+
+// reference : a process touches a page in physical memory, the lock must be held
+func (m *Memory) reference(pid int, entry *PTE) {
+	m.record(entry.page.PageID)
+	m.stamp(pid, entry)
+
+	entry.Referenced = true
+	m.Policy.Referenced(entry.page.PageID)
+}
 
-		// Always add new entry to page table and remove old entry if replaced
-		m.PageTable[p.PageID] = len(m.PhysicalMemory) - 1
+// shootdown : drop a page table entry from every TLB, the lock must be held
+func (m *Memory) shootdown(entry *PTE) {
+	for _, t := range m.tlbs {
+		t.invalidate(entry)
+	}
+}
+
+// PageIn : bring a virtual page of a process into physical memory after a page fault
+func (m *Memory) PageIn(pid int, vpn int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(pid, vpn)
+
+	// Already brought in for someone else sharing it
+	if entry == nil || entry.Valid {
+		return
+	}
+
+	m.load(entry.page, pid)
+}
+
+// Faults : number of translations that found the page outside of physical memory
+func (m *Memory) Faults() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.faults
+}
+
+// record adds a page to the reference string, forgetting the oldest references past MaxReferences
+func (m *Memory) record(id int) {
+	// Trim in bulk so recording stays cheap
+	if len(m.references) >= 2*MaxReferences {
+		m.references = append(m.references[:0], m.references[len(m.references)-MaxReferences:]...)
+	}
+
+	m.references = append(m.references, id)
+
+	if m.Trace != nil {
+		m.Trace.write(id)
+	}
+}
+
+// References : most recent pages translated, in order
+func (m *Memory) References() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refs := m.references
+	if len(refs) > MaxReferences {
+		refs = refs[len(refs)-MaxReferences:]
+	}
+
+	return append([]int{}, refs...)
+}
+
+// PolicyFaults : page faults every replacement policy would have had on the recorded
+// references with as many frames as physical memory has
+func (m *Memory) PolicyFaults() map[string]int {
+	refs := m.References()
+
+	return CompareFaults(refs, len(m.PhysicalMemory))
+}
+
+// COWFaults : number of writes that had to copy a shared page
+func (m *Memory) COWFaults() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.cowFaults
+}
+
+// Shared : number of pages shared by more than one process
+func (m *Memory) Shared() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shared := 0
+	for _, page := range m.pages {
+		if page.refs > 1 {
+			shared++
+		}
+	}
+
+	return shared
+}
+
+// FreeFrames : number of frames in physical memory without a page
+func (m *Memory) FreeFrames() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	free := 0
+	for _, page := range m.PhysicalMemory {
+		if page == nil {
+			free++
+		}
+	}
+
+	return free
+}
+
+// Usage : number of pages in physical and virtual memory
+func (m *Memory) Usage() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Pages worth of memory given to processes
+	if m.Contiguous != nil {
+		return (m.TotalRam - m.Contiguous.free()) / m.PageSize, 0
+	}
+
+	physical := 0
+	for _, page := range m.PhysicalMemory {
+		if page != nil && page != kernelFrame {
+			physical++
+		}
+	}
+
+	return physical, len(m.pages) - physical
+}
+
+// SwapStats : number of pages read from and written to the swap file
+func (m *Memory) SwapStats() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Swap == nil {
+		return 0, 0
+	}
+
+	return m.Swap.ins, m.Swap.outs
+}
+
+// contents : contents of a page, reading it from the swap file if it was written out
+func (m *Memory) contents(p *Page) []byte {
+	if p.contents != nil || p.slot < 0 || m.Swap == nil {
+		return p.contents
+	}
+
+	contents, err := m.Swap.read(p.slot)
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return nil
+	}
+
+	return contents
+}
+
+// load puts a page from virtual memory into a frame for a process, replacing a page if there's no free frame
+func (m *Memory) load(p *Page, pid int) {
+
+	// Read the page back in from the swap file, it should be just like it was when it left
+	p.contents = m.contents(p)
+	if m.checksum(p.contents) != p.checksum {
+		m.corrupted = append(m.corrupted, p.PageID)
+	}
+
+	// Zero whatever couldn't be read so the page is whole again
+	if len(p.contents) < m.PageSize {
+		p.contents = append(p.contents, make([]byte, m.PageSize-len(p.contents))...)
+	}
+
+	// if there is an empty space, put page in empty space
+	frame := -1
+	for i, page := range m.PhysicalMemory {
+		if page == nil {
+			frame = i
+			break
+		}
 	}
 
 	// if there isn't an empty space, run a replace procedure
+	if frame == -1 {
+		victim := m.findVictim(pid)
+		frame = victim.frame
+
+		m.evict(victim)
+	}
+
+	m.PhysicalMemory[frame] = p
+	p.frame = frame
+
+	// Every process mapping the page sees it in the frame now
+	for _, e := range p.entries {
+		e.Frame = frame
+		e.Valid = true
+		e.Dirty = false
+		e.Referenced = false
+	}
+
+	m.Policy.Loaded(p.PageID)
+}
+
+// evict moves a page from its frame to virtual memory, writing it to the swap file if it changed
+func (m *Memory) evict(p *Page) {
+	m.PhysicalMemory[p.frame] = nil
+	p.frame = -1
 
-	// Find victim page
-	i, victimPage := m.findVictim(p.ProcID)
-	if i == -1 {
+	// Contents have to be the same when the page comes back
+	p.checksum = m.checksum(p.contents)
+
+	dirty := false
+	for _, e := range p.entries {
+		dirty = dirty || e.Dirty
+
+		e.Valid = false
+		m.shootdown(e)
+	}
+
+	m.Policy.Removed(p.PageID)
+
+	if m.Swap == nil {
 		return
 	}
 
-	// Fill victim page's spot
-	m.PhysicalMemory[i] = p
+	// Clean pages already have a copy in their slot, or nothing but zeros worth keeping
+	if dirty || (p.slot < 0 && !zeroed(p.contents)) {
+		if p.slot < 0 {
+			p.slot = m.Swap.alloc()
+		}
 
-	// Always add new entry to page table and remove old entry if replaced
-	m.PageTable[p.PageID] = i
-	delete(m.PageTable, victimPage.PageID)
+		if err := m.Swap.write(p.slot, p.contents); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return
+		}
+	}
 
-	// move victim page to virtual memory
-	m.VirtualMemory = append(m.VirtualMemory, victimPage)
+	// Only the swap file has the page now
+	if p.slot >= 0 {
+		p.contents = nil
+	}
 }
 
-// findVictim : find a process to replace the current one with
-func (m *Memory) findVictim(procID int) (int, *Page) {
+// findVictim : have the replacement policy pick a page to replace for a process, physical memory must be full
+func (m *Memory) findVictim(pid int) *Page {
+
+	candidates := []int{}
+
+	// Local replacement only takes the process's own pages
+	if m.Scope == LocalScope {
+		for _, e := range m.tables[pid] {
+			if e.Valid {
+				candidates = append(candidates, e.page.PageID)
+			}
+		}
+	}
 
-	// Literally just find the first page with the same process ID lol
-	for _, v := range m.PageTable {
-		if m.PhysicalMemory[v].ProcID == procID {
-			return v, m.PhysicalMemory[v]
+	// Global replacement, or the process has nothing in RAM to give up
+	if len(candidates) == 0 {
+		for _, page := range m.PhysicalMemory {
+			if page != kernelFrame {
+				candidates = append(candidates, page.PageID)
+			}
 		}
 	}
 
-	return -1, nil
+	return m.pages[m.Policy.Victim(candidates)]
 }
 
-// RemovePages : remove all pages associated with a pid
+// RemovePages : drop the page table of a pid, pages are only freed once no other process shares them
 func (m *Memory) RemovePages(pid int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Remove pages from physical memory
-	for i := len(m.PhysicalMemory) - 1; i >= 0; i-- {
-		page := m.PhysicalMemory[i]
-		if page.ProcID == pid {
-			m.PhysicalMemory = remove(m.PhysicalMemory, i)
+	for _, entry := range m.tables[pid] {
+		page := entry.page
 
-			// Update page table
-			delete(m.PageTable, page.PageID)
+		page.refs--
+		page.entries = removeEntry(page.entries, entry)
+		m.shootdown(entry)
+		if page.refs > 0 {
+			continue
 		}
+
+		m.free(page)
 	}
 
-	// Remove pages from virtual memory
-	for i := len(m.VirtualMemory) - 1; i >= 0; i-- {
-		page := m.VirtualMemory[i]
-		if page.ProcID == pid {
+	// Shared memory is only freed with the last process attached
+	for key := range m.attached[pid] {
+		m.detach(pid, key)
+	}
 
-			m.VirtualMemory = remove(m.VirtualMemory, i)
-		}
+	delete(m.tables, pid)
+	delete(m.segments, pid)
+	delete(m.clocks, pid)
+	delete(m.procFaults, pid)
+
+	if m.Contiguous != nil {
+		m.Contiguous.release(pid)
+		delete(m.Contiguous.inherited, pid)
+	}
+}
+
+// free : forget a page nobody maps anymore, the lock must be held
+func (m *Memory) free(page *Page) {
+	delete(m.pages, page.PageID)
+
+	// Free its slot in the swap file
+	if page.slot >= 0 {
+		m.Swap.release(page.slot)
+	}
+
+	// Remove page from physical memory
+	if page.frame >= 0 {
+		m.PhysicalMemory[page.frame] = nil
+		m.Policy.Removed(page.PageID)
 	}
 }
 
-func remove(slice []*Page, s int) []*Page {
-	slice[s] = slice[len(slice)-1] // Copy last element to index i.
-	// slice[len(slice)-1] = nil   	// Erase last element (write zero value)
-	slice = slice[:len(slice)-1] // Truncate slice.
+// removeEntry takes a page table entry out of the entries mapping a page
+func removeEntry(entries []*PTE, entry *PTE) []*PTE {
+	for i, e := range entries {
+		if e == entry {
+			return append(entries[:i], entries[i+1:]...)
+		}
+	}
 
-	return slice
+	return entries
 }
@@ -1 +1,194 @@
 package memory
+
+import "testing"
+
+func TestForkSharesPagesCopyOnWrite(t *testing.T) {
+	m := InitMemory(32, 4096)
+
+	m.Add(64, 1)
+	if pages := m.Fork(1, 2); pages != 2 {
+		t.Fatalf("child should get both of the parent's pages. got=%d", pages)
+	}
+
+	if m.Shared() != 2 {
+		t.Errorf("both pages should be shared. got=%d", m.Shared())
+	}
+
+	// First write copies the page
+	if !m.Write(2, 1) {
+		t.Fatalf("writing a shared page should copy it")
+	}
+
+	child, parent := m.PageTable(2), m.PageTable(1)
+	if !child[1].Valid || !child[1].Dirty || parent[1].Valid {
+		t.Errorf("copy should be made in RAM and the original left alone")
+	}
+
+	// The copy belongs to the child alone and the original to the parent alone
+	if m.Write(2, 1) {
+		t.Errorf("copy shouldn't be copied again")
+	}
+
+	if m.Write(1, 1) {
+		t.Errorf("parent has the original to itself now")
+	}
+
+	if m.COWFaults() != 1 || m.Shared() != 1 {
+		t.Errorf("wrong stats. faults=%d shared=%d", m.COWFaults(), m.Shared())
+	}
+}
+
+func TestRemovePagesKeepsSharedPages(t *testing.T) {
+	m := InitMemory(32, 4096)
+
+	m.Add(64, 1)
+	m.Fork(1, 2)
+
+	m.RemovePages(1)
+
+	if _, virtual := m.Usage(); virtual != 2 {
+		t.Fatalf("child still uses the pages. got=%d pages", virtual)
+	}
+
+	m.RemovePages(2)
+
+	if _, virtual := m.Usage(); virtual != 0 {
+		t.Errorf("pages should be freed with the last process. got=%d pages", virtual)
+	}
+}
+
+func TestTranslateFaultsUntilPagedIn(t *testing.T) {
+	// Room for two pages
+	m := InitMemory(32, 64)
+
+	m.Add(96, 1)
+
+	// Pages start out on disk
+	if _, err := m.Translate(1, 40, ProtRead); err != ErrPageFault {
+		t.Fatalf("first touch of a page should fault. got=%v", err)
+	}
+
+	m.PageIn(1, 1)
+
+	addr, err := m.Translate(1, 40, ProtRead)
+	if err != nil {
+		t.Fatalf("page should be in after paging it in. got=%v", err)
+	}
+
+	table := m.PageTable(1)
+	if !table[1].Valid || !table[1].Referenced || addr != table[1].Frame*32+8 {
+		t.Errorf("wrong translation. addr=%d entry=%+v", addr, table[1])
+	}
+
+	// Filling memory pushes one of the process's pages back out
+	m.PageIn(1, 0)
+	m.PageIn(1, 2)
+
+	if physical, virtual := m.Usage(); physical != 2 || virtual != 1 {
+		t.Errorf("a page should have been evicted. physical=%d virtual=%d", physical, virtual)
+	}
+
+	if table := m.PageTable(1); table[1].Valid {
+		t.Errorf("first page brought in should be replaced first")
+	}
+
+	if m.Faults() != 1 {
+		t.Errorf("wrong number of faults. got=%d", m.Faults())
+	}
+
+	if _, err := m.Translate(1, 96, ProtRead); err != ErrBadAddress {
+		t.Errorf("address past the process's pages shouldn't translate. got=%v", err)
+	}
+
+	m.Protect(1, 0, ProtRead|ProtExec)
+	if _, err := m.Translate(1, 0, ProtWrite); err != ErrProtection {
+		t.Errorf("read only page shouldn't translate for a write. got=%v", err)
+	}
+}
+
+func TestSeparateMemories(t *testing.T) {
+	a, b := InitMemory(32, 4096), InitMemory(32, 4096)
+
+	a.Add(32, 1)
+	b.Add(32, 1)
+
+	a.Translate(1, 0, ProtRead)
+	b.Translate(1, 0, ProtRead)
+
+	// Page IDs are numbered per memory
+	if refs := b.References(); len(refs) != 1 || refs[0] != a.References()[0] {
+		t.Errorf("memories shouldn't affect each other. got=%v", refs)
+	}
+}
+
+func TestBeladysAnomaly(t *testing.T) {
+	refs := []int{1, 2, 3, 4, 1, 2, 5, 1, 2, 3, 4, 5}
+
+	tests := []struct {
+		policy string
+		frames int
+		faults int
+	}{
+		{"fifo", 3, 9},
+		{"fifo", 4, 10},
+		{"lru", 3, 10},
+		{"lru", 4, 8},
+		{"optimal", 3, 7},
+		{"optimal", 4, 6},
+		{"clock", 3, 9},
+		{"lfu", 3, 10},
+	}
+
+	for _, tt := range tests {
+		policy, err := NewReplacementPolicy(tt.policy, refs)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.policy, err)
+		}
+
+		if faults := Replay(policy, refs, tt.frames); faults != tt.faults {
+			t.Errorf("%s with %d frames: wrong number of faults. want=%d, got=%d", tt.policy, tt.frames, tt.faults, faults)
+		}
+	}
+
+	if _, err := NewReplacementPolicy("random", nil); err == nil {
+		t.Errorf("unknown policy should be an error")
+	}
+}
+
+func TestClockWithEmptyRing(t *testing.T) {
+	c := NewClock()
+
+	if victim := c.Victim([]int{3, 4}); victim != 3 {
+		t.Errorf("clock with nothing loaded should pick the first candidate. got=%d", victim)
+	}
+
+	c.Loaded(1)
+	c.Removed(1)
+
+	if victim := c.Victim([]int{5}); victim != 5 {
+		t.Errorf("clock with every page removed should pick the first candidate. got=%d", victim)
+	}
+}
+
+func TestLocalReplacementKeepsOtherProcessesPages(t *testing.T) {
+	// Room for two pages
+	m := InitMemory(32, 64)
+	m.Policy = NewLRU()
+	m.Scope = LocalScope
+
+	m.Add(32, 1)
+	m.Add(64, 2)
+
+	m.PageIn(1, 0)
+	m.PageIn(2, 0)
+	m.PageIn(2, 1)
+
+	// Process 1's page is the least recently used, but process 2 has its own to replace
+	if _, err := m.Translate(1, 0, ProtRead); err != nil {
+		t.Errorf("local replacement shouldn't take process 1's page")
+	}
+
+	if _, err := m.Translate(2, 0, ProtRead); err != ErrPageFault {
+		t.Errorf("process 2 should have replaced its own page")
+	}
+}
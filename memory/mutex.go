@@ -1,21 +1,49 @@
 package memory
 
-// Mutex : Mutex lock
+import (
+	"fmt"
+	"sync"
+)
+
+// Mutex : Mutex lock that keeps track of the process holding it
 type Mutex struct {
 	locked bool
+	owner  int
+	mu     sync.Mutex // makes checking and setting the lock atomic
 }
 
-// Acquire : lock the mutex lock
-func (m *Mutex) Acquire() bool {
+// Acquire : lock the mutex lock for a process, false if it's already held
+func (m *Mutex) Acquire(pid int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.locked {
 		return false
 	}
 
 	m.locked = true
+	m.owner = pid
 	return true
 }
 
-// Release : unlock the mutex lock
-func (m *Mutex) Release() {
+// Release : unlock the mutex lock, only the process holding it can
+func (m *Mutex) Release(pid int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.locked || m.owner != pid {
+		return fmt.Errorf("process %d doesn't hold the lock", pid)
+	}
+
 	m.locked = false
+	m.owner = 0
+	return nil
+}
+
+// Owner : process holding the lock, false if it isn't locked
+func (m *Mutex) Owner() (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.owner, m.locked
 }
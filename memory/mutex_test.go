@@ -0,0 +1,31 @@
+package memory
+
+import "testing"
+
+func TestMutex(t *testing.T) {
+	m := &Mutex{}
+
+	if !m.Acquire(1) {
+		t.Fatalf("unlocked mutex should be acquired")
+	}
+
+	if m.Acquire(2) {
+		t.Errorf("held mutex shouldn't be acquired")
+	}
+
+	if err := m.Release(2); err == nil {
+		t.Errorf("only the owner should release the mutex")
+	}
+
+	if owner, locked := m.Owner(); !locked || owner != 1 {
+		t.Errorf("wrong owner. want=1, got=%d (locked=%t)", owner, locked)
+	}
+
+	if err := m.Release(1); err != nil {
+		t.Fatalf("owner should release the mutex. got=%v", err)
+	}
+
+	if _, locked := m.Owner(); locked {
+		t.Errorf("released mutex shouldn't be locked")
+	}
+}
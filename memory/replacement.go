@@ -0,0 +1,358 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+
+	// GlobalScope : a faulting process can take a frame from any process
+	GlobalScope = "global"
+
+	// LocalScope : a faulting process replaces one of its own pages when it has any in RAM
+	LocalScope = "local"
+
+	// MaxReferences : number of page references memory remembers for replaying
+	MaxReferences = 10000
+)
+
+// Policies : names of every replacement policy, in the order they're reported
+var Policies = []string{"fifo", "lru", "clock", "lfu", "optimal"}
+
+// ReplacementPolicy : decides which page leaves physical memory when a page comes in and every frame is taken
+//
+// Pages are identified by their PageID rather than their frame since a page
+// can come back into a different frame after it's swapped out.
+type ReplacementPolicy interface {
+
+	// Name : name of the policy in the config
+	Name() string
+
+	// Loaded : the page was put into a frame
+	Loaded(id int)
+
+	// Referenced : the page was touched while in a frame
+	Referenced(id int)
+
+	// Removed : the page left physical memory
+	Removed(id int)
+
+	// Victim : pick the page to replace out of the candidates, there is at least one
+	Victim(candidates []int) int
+}
+
+// NewReplacementPolicy : create the replacement policy with the name from the config,
+// optimal looks ahead in the reference string refs
+func NewReplacementPolicy(name string, refs []int) (ReplacementPolicy, error) {
+	switch name {
+	case "fifo":
+		return NewFIFO(), nil
+	case "lru":
+		return NewLRU(), nil
+	case "clock":
+		return NewClock(), nil
+	case "lfu":
+		return NewLFU(), nil
+	case "optimal":
+		return NewOptimal(refs), nil
+	}
+
+	return nil, fmt.Errorf("unknown page replacement policy %q", name)
+}
+
+// oldest : candidate with the lowest stamp, candidates without one count as the oldest
+func oldest(candidates []int, stamps map[int]int) int {
+	victim := candidates[0]
+	for _, id := range candidates[1:] {
+		if stamps[id] < stamps[victim] {
+			victim = id
+		}
+	}
+
+	return victim
+}
+
+// FIFO : replace the page that has been in memory the longest
+type FIFO struct {
+	clock  int
+	loaded map[int]int // When each page was loaded
+}
+
+// NewFIFO : create first in first out replacement
+func NewFIFO() *FIFO {
+	return &FIFO{loaded: make(map[int]int)}
+}
+
+// Name : fifo
+func (f *FIFO) Name() string { return "fifo" }
+
+// Loaded : remember when the page came in
+func (f *FIFO) Loaded(id int) {
+	f.clock++
+	f.loaded[id] = f.clock
+}
+
+// Referenced : doesn't matter to fifo
+func (f *FIFO) Referenced(id int) {}
+
+// Removed : forget the page
+func (f *FIFO) Removed(id int) { delete(f.loaded, id) }
+
+// Victim : first candidate loaded
+func (f *FIFO) Victim(candidates []int) int { return oldest(candidates, f.loaded) }
+
+// LRU : replace the page that hasn't been touched for the longest
+type LRU struct {
+	clock int
+	used  map[int]int // When each page was last touched
+}
+
+// NewLRU : create least recently used replacement
+func NewLRU() *LRU {
+	return &LRU{used: make(map[int]int)}
+}
+
+// Name : lru
+func (l *LRU) Name() string { return "lru" }
+
+// Loaded : loading a page touches it
+func (l *LRU) Loaded(id int) { l.Referenced(id) }
+
+// Referenced : remember when the page was touched
+func (l *LRU) Referenced(id int) {
+	l.clock++
+	l.used[id] = l.clock
+}
+
+// Removed : forget the page
+func (l *LRU) Removed(id int) { delete(l.used, id) }
+
+// Victim : candidate touched the longest ago
+func (l *LRU) Victim(candidates []int) int { return oldest(candidates, l.used) }
+
+// Clock : second chance, pages sit on a circle in the order they were loaded and a
+// hand passes over the ones referenced since it last came by
+type Clock struct {
+	ring       []int        // Pages in the order they were loaded
+	hand       int          // Index in the ring the next search starts at
+	referenced map[int]bool // Reference bit of each page
+}
+
+// NewClock : create second chance replacement
+func NewClock() *Clock {
+	return &Clock{ring: []int{}, referenced: make(map[int]bool)}
+}
+
+// Name : clock
+func (c *Clock) Name() string { return "clock" }
+
+// Loaded : put the page behind the hand with its reference bit set
+func (c *Clock) Loaded(id int) {
+	c.ring = append(c.ring[:c.hand], append([]int{id}, c.ring[c.hand:]...)...)
+	c.hand = (c.hand + 1) % len(c.ring)
+	c.referenced[id] = true
+}
+
+// Referenced : set the page's reference bit
+func (c *Clock) Referenced(id int) { c.referenced[id] = true }
+
+// Removed : take the page off the circle
+func (c *Clock) Removed(id int) {
+	for i, page := range c.ring {
+		if page != id {
+			continue
+		}
+
+		c.ring = append(c.ring[:i], c.ring[i+1:]...)
+		if i < c.hand {
+			c.hand--
+		}
+
+		if c.hand >= len(c.ring) {
+			c.hand = 0
+		}
+
+		break
+	}
+
+	delete(c.referenced, id)
+}
+
+// Victim : first candidate after the hand without its reference bit, clearing bits along the way
+func (c *Clock) Victim(candidates []int) int {
+	// Nothing was loaded for the hand to pass over
+	if len(c.ring) == 0 {
+		return candidates[0]
+	}
+
+	candidate := make(map[int]bool, len(candidates))
+	for _, id := range candidates {
+		candidate[id] = true
+	}
+
+	// Every bit is clear after one trip around
+	for i := 0; i <= 2*len(c.ring); i++ {
+		id := c.ring[c.hand]
+
+		if candidate[id] {
+			if !c.referenced[id] {
+				return id
+			}
+
+			c.referenced[id] = false
+		}
+
+		c.hand = (c.hand + 1) % len(c.ring)
+	}
+
+	return candidates[0]
+}
+
+// LFU : replace the page touched the fewest times, ties go to the one loaded first
+type LFU struct {
+	clock  int
+	uses   map[int]int // Number of times each page was touched
+	loaded map[int]int // When each page was loaded
+}
+
+// NewLFU : create least frequently used replacement
+func NewLFU() *LFU {
+	return &LFU{uses: make(map[int]int), loaded: make(map[int]int)}
+}
+
+// Name : lfu
+func (l *LFU) Name() string { return "lfu" }
+
+// Loaded : the page starts over with one use
+func (l *LFU) Loaded(id int) {
+	l.clock++
+	l.loaded[id] = l.clock
+	l.uses[id] = 1
+}
+
+// Referenced : count the use
+func (l *LFU) Referenced(id int) { l.uses[id]++ }
+
+// Removed : forget the page
+func (l *LFU) Removed(id int) {
+	delete(l.uses, id)
+	delete(l.loaded, id)
+}
+
+// Victim : candidate with the fewest uses
+func (l *LFU) Victim(candidates []int) int {
+	victim := candidates[0]
+	for _, id := range candidates[1:] {
+		if l.uses[id] < l.uses[victim] || (l.uses[id] == l.uses[victim] && l.loaded[id] < l.loaded[victim]) {
+			victim = id
+		}
+	}
+
+	return victim
+}
+
+// Optimal : Belady's algorithm, replace the page that won't be used for the longest
+//
+// It needs to know the future, so it follows along a recorded reference string
+// and only makes sense when the same references are replayed. Past the end of
+// the reference string no page is used again and it falls back to fifo.
+type Optimal struct {
+	refs []int // Reference string being replayed
+	next int   // Index in refs of the next reference
+	fifo *FIFO // Tie breaker for pages that aren't used again
+}
+
+// NewOptimal : create optimal replacement for the reference string
+func NewOptimal(refs []int) *Optimal {
+	return &Optimal{refs: refs, fifo: NewFIFO()}
+}
+
+// Name : optimal
+func (o *Optimal) Name() string { return "optimal" }
+
+// Loaded : a page comes in for the current reference
+func (o *Optimal) Loaded(id int) {
+	o.fifo.Loaded(id)
+	o.Referenced(id)
+}
+
+// Referenced : move past the reference if it's the one expected
+func (o *Optimal) Referenced(id int) {
+	if o.next < len(o.refs) && o.refs[o.next] == id {
+		o.next++
+	}
+}
+
+// Removed : forget the page
+func (o *Optimal) Removed(id int) { o.fifo.Removed(id) }
+
+// Victim : candidate whose next use is furthest away
+func (o *Optimal) Victim(candidates []int) int {
+	nextUse := make(map[int]int, len(candidates))
+	for _, id := range candidates {
+		nextUse[id] = math.MaxInt32
+	}
+
+	// The page being brought in is refs[next], look past it until every candidate turns up
+	left := len(candidates)
+	for i := o.next + 1; i < len(o.refs) && left > 0; i++ {
+		if use, ok := nextUse[o.refs[i]]; ok && use == math.MaxInt32 {
+			nextUse[o.refs[i]] = i
+			left--
+		}
+	}
+
+	victim := -1
+	for _, id := range candidates {
+		if victim == -1 || nextUse[id] > nextUse[victim] ||
+			(nextUse[id] == nextUse[victim] && o.fifo.loaded[id] < o.fifo.loaded[victim]) {
+			victim = id
+		}
+	}
+
+	return victim
+}
+
+// Replay : number of page faults the policy has on the reference string with that many frames
+func Replay(policy ReplacementPolicy, refs []int, frames int) int {
+	resident := make(map[int]bool, frames)
+	faults := 0
+
+	for _, id := range refs {
+		if resident[id] {
+			policy.Referenced(id)
+			continue
+		}
+
+		faults++
+
+		if len(resident) >= frames {
+			candidates := make([]int, 0, len(resident))
+			for page := range resident {
+				candidates = append(candidates, page)
+			}
+
+			victim := policy.Victim(candidates)
+			delete(resident, victim)
+			policy.Removed(victim)
+		}
+
+		resident[id] = true
+		policy.Loaded(id)
+	}
+
+	return faults
+}
+
+// CompareFaults : page faults of every policy replaying the same reference string with that many frames
+func CompareFaults(refs []int, frames int) map[string]int {
+	faults := make(map[string]int, len(Policies))
+
+	for _, name := range Policies {
+		policy, _ := NewReplacementPolicy(name, refs)
+		faults[name] = Replay(policy, refs, frames)
+	}
+
+	return faults
+}
@@ -0,0 +1,249 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+
+	// Segment numbers, an address's segment is in its top bits
+
+	// CodeSegment : the program
+	CodeSegment = iota
+
+	// DataSegment : static data
+	DataSegment
+
+	// HeapSegment : memory allocated while running
+	HeapSegment
+
+	// StackSegment : the stack
+	StackSegment
+
+	// OffsetBits : bits of an address holding the offset into its segment, the rest pick the segment
+	// and stay below the shared memory window
+	OffsetBits = 13
+)
+
+// Segments : name of each segment, indexed by segment number
+var Segments = []string{"code", "data", "heap", "stack"}
+
+// segmentProtection : kinds of access each segment allows, indexed by segment number
+var segmentProtection = []int{ProtRead | ProtExec, ProtRead | ProtWrite, ProtRead | ProtWrite, ProtRead | ProtWrite}
+
+// Segment : entry of a process's segment table
+type Segment struct {
+	Name       string // code, data, heap or stack
+	Base       int    // First byte in physical memory, or the first virtual page with paged segmentation
+	Limit      int    // Number of bytes in the segment, offsets past it are segment faults
+	Protection int    // Kinds of access allowed, ProtRead | ProtWrite | ProtExec
+}
+
+// SegmentAddress : virtual address of an offset into a segment
+func SegmentAddress(segment int, offset int) int {
+	return segment<<OffsetBits | offset
+}
+
+// ParseAddress : parse an address written as segment:offset, like data:16, shm:key:offset for
+// shared memory, like shm:2:16, or as a plain number
+func ParseAddress(field string) (int, error) {
+	parts := strings.Split(field, ":")
+	if len(parts) == 1 {
+		return strconv.Atoi(field)
+	}
+
+	if parts[0] == "shm" {
+		if len(parts) != 3 {
+			return 0, fmt.Errorf("address %q should look like shm:key:offset", field)
+		}
+
+		key, err := strconv.Atoi(parts[1])
+		if err != nil || key < 0 || key >= MaxSharedKeys {
+			return 0, fmt.Errorf("address %q has a bad shared memory key", field)
+		}
+
+		offset, err := strconv.Atoi(parts[2])
+		if err != nil || offset < 0 || offset >= MaxSharedSize {
+			return 0, fmt.Errorf("address %q has a bad offset", field)
+		}
+
+		return SharedAddress(key, offset), nil
+	}
+
+	segment := segmentNumber(parts[0])
+	if len(parts) != 2 || segment == -1 {
+		return 0, fmt.Errorf("address %q should look like segment:offset", field)
+	}
+
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil || offset < 0 || offset >= 1<<OffsetBits {
+		return 0, fmt.Errorf("address %q has a bad offset", field)
+	}
+
+	return SegmentAddress(segment, offset), nil
+}
+
+// FlatAddress : address of a segmented address with the segments laid out one after another from 0, for memory
+// without segmentation, ErrSegmentFault if the offset is past the end of its segment
+func FlatAddress(segments []Segment, vaddr int) (int, error) {
+	s, offset := vaddr>>OffsetBits, vaddr&(1<<OffsetBits-1)
+	if s >= len(segments) || offset >= segments[s].Limit {
+		return 0, ErrSegmentFault
+	}
+
+	base := 0
+	for _, seg := range segments[:s] {
+		base += seg.Limit
+	}
+
+	return base + offset, nil
+}
+
+// ParseSegments : parse a segment table declared as name:size fields, like code:64 stack:32
+//
+// Segments left out are empty, so touching them is a segment fault.
+func ParseSegments(fields []string) ([]Segment, error) {
+	segments := make([]Segment, len(Segments))
+	for i, name := range Segments {
+		segments[i] = Segment{Name: name, Protection: segmentProtection[i]}
+	}
+
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+
+		parts := strings.Split(field, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("segment %q should look like name:size", field)
+		}
+
+		i := segmentNumber(parts[0])
+		if i == -1 {
+			return nil, fmt.Errorf("segment %q should be one of %s", field, strings.Join(Segments, ", "))
+		}
+
+		size, err := strconv.Atoi(parts[1])
+		if err != nil || size < 0 || size >= 1<<OffsetBits {
+			return nil, fmt.Errorf("segment %q has a bad size", field)
+		}
+
+		segments[i].Limit = size
+	}
+
+	return segments, nil
+}
+
+// segmentNumber : number of the segment with that name, -1 if there isn't one
+func segmentNumber(name string) int {
+	for i, segment := range Segments {
+		if segment == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// SetSegments : give a process its segment table before its memory is added or placed
+func (m *Memory) SetSegments(pid int, segments []Segment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.segments[pid] = append([]Segment{}, segments...)
+}
+
+// segmentTable : segment table of a process, a code segment covering all of its memory if it
+// didn't declare any, the lock must be held
+func (m *Memory) segmentTable(pid int, requirement int) []Segment {
+	if _, ok := m.segments[pid]; !ok {
+		m.segments[pid] = []Segment{{Name: Segments[CodeSegment], Limit: requirement, Protection: ProtAll}}
+	}
+
+	return m.segments[pid]
+}
+
+// addSegments : pages for every segment of a process, each segment starting on a new page, the lock must be held
+func (m *Memory) addSegments(pid int, requirement int) {
+	segments := m.segmentTable(pid, requirement)
+
+	for i, seg := range segments {
+		segments[i].Base = len(m.tables[pid])
+
+		numOfPages := int(math.Ceil(float64(seg.Limit) / float64(m.PageSize)))
+		for j := 0; j < numOfPages; j++ {
+			m.addPage(pid, seg.Protection)
+		}
+	}
+}
+
+// SegmentTable : copy of the segment table of a process, with the base of each segment in physical memory
+// or in virtual pages with paged segmentation
+func (m *Memory) SegmentTable(pid int) []Segment {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	table := append([]Segment{}, m.segments[pid]...)
+
+	if m.Contiguous != nil {
+		regions := m.Contiguous.regions[pid]
+		for i := range table {
+			if i < len(regions) {
+				table[i].Base = regions[i].Base
+			}
+		}
+	}
+
+	return table
+}
+
+// segment : address an offset into a segment of a process maps to, a physical address with pure segmentation
+// and a virtual one into its pages with paged segmentation, the lock must be held
+func (m *Memory) segment(pid int, vaddr int, access int) (int, error) {
+	if vaddr < 0 {
+		return -1, ErrSegmentFault
+	}
+
+	s, offset := vaddr>>OffsetBits, vaddr&(1<<OffsetBits-1)
+
+	table := m.segments[pid]
+	if s >= len(table) || offset >= table[s].Limit {
+		return -1, ErrSegmentFault
+	}
+
+	if table[s].Protection&access == 0 {
+		return -1, ErrProtection
+	}
+
+	// Segments get their own holes without paging
+	if m.Contiguous != nil {
+		regions := m.Contiguous.regions[pid]
+		if s >= len(regions) {
+			return -1, ErrSegmentFault
+		}
+
+		return regions[s].Base + offset, nil
+	}
+
+	return table[s].Base*m.PageSize + offset, nil
+}
+
+// Page : virtual page number holding a virtual address of a process, -1 if the address isn't in a segment
+func (m *Memory) Page(pid int, vaddr int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.Segmented || vaddr >= SharedBase {
+		return vaddr / m.PageSize
+	}
+
+	linear, err := m.segment(pid, vaddr, ProtAll)
+	if err != nil || m.Contiguous != nil {
+		return -1
+	}
+
+	return linear / m.PageSize
+}
@@ -0,0 +1,111 @@
+package memory
+
+import "testing"
+
+// newSegmented makes segmented memory with process 1 laid out as a 40 byte code segment and a 16 byte stack
+func newSegmented(t *testing.T, contiguous bool) *Memory {
+	m := InitMemory(16, 256)
+	m.Segmented = true
+
+	if contiguous {
+		m.AddContiguous("first", false)
+	}
+
+	segments, err := ParseSegments([]string{"code:40", "stack:16"})
+	if err != nil {
+		t.Fatalf("segments should parse. got=%v", err)
+	}
+
+	m.SetSegments(1, segments)
+
+	return m
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		field string
+		addr  int
+		ok    bool
+	}{
+		{"70", 70, true},
+		{"code:5", 5, true},
+		{"stack:16", 3<<OffsetBits | 16, true},
+		{"bss:4", 0, false},
+		{"data:-1", 0, false},
+		{"shm:2:8", SharedBase + 2*MaxSharedSize + 8, true},
+	}
+
+	for _, tt := range tests {
+		addr, err := ParseAddress(tt.field)
+		if (err == nil) != tt.ok || addr != tt.addr {
+			t.Errorf("wrong address for %q. want=%d, got=%d err=%v", tt.field, tt.addr, addr, err)
+		}
+	}
+}
+
+func TestPagedSegmentation(t *testing.T) {
+	m := newSegmented(t, false)
+
+	// Three pages of code then one of stack
+	if pages := m.Add(0, 1); pages != 4 {
+		t.Fatalf("segments should start on their own pages. got=%d pages", pages)
+	}
+
+	table := m.SegmentTable(1)
+	if table[StackSegment].Base != 3 || table[DataSegment].Limit != 0 {
+		t.Errorf("wrong segment table. got=%v", table)
+	}
+
+	stack := SegmentAddress(StackSegment, 4)
+	if vpn := m.Page(1, stack); vpn != 3 {
+		t.Errorf("stack should be on the page after the code. got=%d", vpn)
+	}
+
+	tests := []struct {
+		addr   int
+		access int
+		err    error
+	}{
+		{SegmentAddress(CodeSegment, 39), ProtExec, ErrPageFault},
+		{SegmentAddress(CodeSegment, 40), ProtRead, ErrSegmentFault},
+		{SegmentAddress(CodeSegment, 0), ProtWrite, ErrProtection},
+		{SegmentAddress(DataSegment, 0), ProtRead, ErrSegmentFault},
+		{stack, ProtWrite, ErrPageFault},
+	}
+
+	for _, tt := range tests {
+		if _, err := m.Translate(1, tt.addr, tt.access); err != tt.err {
+			t.Errorf("wrong error for address %d. want=%v, got=%v", tt.addr, tt.err, err)
+		}
+	}
+
+	m.PageIn(1, 3)
+	if addr, err := m.Translate(1, stack, ProtRead); err != nil || addr%m.PageSize != 4 {
+		t.Errorf("stack should be in memory. addr=%d err=%v", addr, err)
+	}
+}
+
+func TestPureSegmentationPlacesEachSegment(t *testing.T) {
+	m := newSegmented(t, true)
+
+	m.Place(2, 32)
+	if pages, err := m.Place(1, 0); err != nil || pages != 4 {
+		t.Fatalf("both segments should be placed. pages=%d err=%v", pages, err)
+	}
+
+	// Process 2 takes the start of memory and the code segment is rounded up to 48 bytes
+	if addr, _ := m.Translate(1, SegmentAddress(StackSegment, 4), ProtRead); addr != 32+48+4 {
+		t.Errorf("stack should be placed after the code. got=%d", addr)
+	}
+
+	if _, err := m.Translate(1, SegmentAddress(StackSegment, 16), ProtRead); err != ErrSegmentFault {
+		t.Errorf("offset past the stack should be a segment fault. got=%v", err)
+	}
+
+	m.RemovePages(2)
+	m.Compact()
+
+	if table := m.SegmentTable(1); table[CodeSegment].Base != 0 || table[StackSegment].Base != 48 {
+		t.Errorf("segments should follow compaction. got=%v", table)
+	}
+}
@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+
+	// SharedBase : first virtual address of the shared memory window, every process sees key k at SharedAddress(k, 0)
+	SharedBase = 1 << 15
+
+	// MaxSharedSize : most bytes in a shared segment
+	MaxSharedSize = 1024
+
+	// MaxSharedKeys : keys go from 0 up to this, so every segment fits in the window
+	MaxSharedKeys = (1<<16 - SharedBase) / MaxSharedSize
+)
+
+var (
+
+	// ErrNoSegment : there's no shared segment with that key
+	ErrNoSegment = errors.New("no shared segment with that key")
+)
+
+// sharedSegment : memory any process can attach to by key
+type sharedSegment struct {
+	key      int
+	size     int
+	pages    []*Page // Made when the first process attaches, so a segment nobody attaches to takes no memory
+	attached int     // Processes attached, the segment is freed when the last one detaches
+}
+
+// SharedSegment : copy of a shared segment for displaying
+type SharedSegment struct {
+	Key      int // Key processes attach with
+	Size     int // Bytes in the segment
+	Attached int // Processes attached
+}
+
+// SharedAddress : virtual address of an offset into the shared segment with a key
+func SharedAddress(key int, offset int) int {
+	return SharedBase + key*MaxSharedSize + offset
+}
+
+// SharedGet : make a shared segment of size bytes for a key if there isn't one yet, a process attaches to it with Attach
+// and its pages are only made then
+func (m *Memory) SharedGet(key int, size int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Contiguous != nil {
+		return fmt.Errorf("shared memory needs paging")
+	}
+
+	if key < 0 || key >= MaxSharedKeys {
+		return fmt.Errorf("shared memory key %d should be below %d", key, MaxSharedKeys)
+	}
+
+	if size <= 0 || size > MaxSharedSize {
+		return fmt.Errorf("shared segment of %d bytes should be 1 to %d", size, MaxSharedSize)
+	}
+
+	if _, ok := m.shared[key]; ok {
+		return nil
+	}
+
+	m.shared[key] = &sharedSegment{key: key, size: size}
+
+	return nil
+}
+
+// Attach : map the shared segment with a key into a process's address space at SharedAddress(key, 0)
+func (m *Memory) Attach(pid int, key int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seg, ok := m.shared[key]
+	if !ok {
+		return ErrNoSegment
+	}
+
+	if _, ok := m.attached[pid][key]; ok {
+		return fmt.Errorf("shared segment %d is already attached", key)
+	}
+
+	m.attach(pid, seg)
+
+	return nil
+}
+
+// attach : page table entries for every page of a shared segment, making the pages for the first process, the lock must be held
+func (m *Memory) attach(pid int, seg *sharedSegment) {
+	if seg.pages == nil {
+		numOfPages := int(math.Ceil(float64(seg.size) / float64(m.PageSize)))
+		for i := 0; i < numOfPages; i++ {
+			p := m.newPage(pid, make([]byte, m.PageSize))
+			p.shared = true
+			p.refs = 0
+
+			seg.pages = append(seg.pages, p)
+		}
+	}
+
+	entries := make([]*PTE, len(seg.pages))
+
+	for i, p := range seg.pages {
+		entry := &PTE{
+			Frame:      p.frame,
+			Valid:      p.frame >= 0,
+			Protection: ProtRead | ProtWrite,
+			page:       p,
+		}
+
+		p.refs++
+		p.entries = append(p.entries, entry)
+		entries[i] = entry
+	}
+
+	if m.attached[pid] == nil {
+		m.attached[pid] = make(map[int][]*PTE)
+	}
+
+	m.attached[pid][seg.key] = entries
+	seg.attached++
+}
+
+// Detach : unmap the shared segment with a key from a process, the segment is freed once nobody is attached
+func (m *Memory) Detach(pid int, key int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.attached[pid][key]; !ok {
+		return ErrNoSegment
+	}
+
+	m.detach(pid, key)
+
+	return nil
+}
+
+// detach : drop a process's entries for a shared segment, freeing it with the last one, the lock must be held
+func (m *Memory) detach(pid int, key int) {
+	seg := m.shared[key]
+
+	for _, entry := range m.attached[pid][key] {
+		entry.page.refs--
+		entry.page.entries = removeEntry(entry.page.entries, entry)
+		m.shootdown(entry)
+	}
+
+	delete(m.attached[pid], key)
+	if len(m.attached[pid]) == 0 {
+		delete(m.attached, pid)
+	}
+
+	seg.attached--
+	if seg.attached > 0 {
+		return
+	}
+
+	for _, p := range seg.pages {
+		m.free(p)
+	}
+
+	delete(m.shared, key)
+}
+
+// sharedEntry : page table entry for a virtual page in the shared memory window, the lock must be held
+func (m *Memory) sharedEntry(pid int, vpn int) *PTE {
+	offset := vpn*m.PageSize - SharedBase
+
+	entries := m.attached[pid][offset/MaxSharedSize]
+	i := offset % MaxSharedSize / m.PageSize
+	if i >= len(entries) {
+		return nil
+	}
+
+	return entries[i]
+}
+
+// SharedSegments : every shared segment by key
+func (m *Memory) SharedSegments() []SharedSegment {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	segments := make([]SharedSegment, 0, len(m.shared))
+	for _, seg := range m.shared {
+		segments = append(segments, SharedSegment{Key: seg.key, Size: seg.size, Attached: seg.attached})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Key < segments[j].Key
+	})
+
+	return segments
+}
@@ -0,0 +1,111 @@
+package memory
+
+import "testing"
+
+func TestSharedSegmentMapsSameFrames(t *testing.T) {
+	m := InitMemory(32, 256)
+
+	m.Add(32, 1)
+	m.Add(32, 2)
+
+	if err := m.SharedGet(1, 64); err != nil {
+		t.Fatalf("segment should be made. got=%v", err)
+	}
+
+	for _, pid := range []int{1, 2} {
+		if err := m.Attach(pid, 1); err != nil {
+			t.Fatalf("process %d should attach. got=%v", pid, err)
+		}
+	}
+
+	if err := m.Attach(1, 1); err == nil {
+		t.Errorf("attaching twice should fail")
+	}
+
+	addr := SharedAddress(1, 40)
+	touch(m, 1, addr/m.PageSize)
+
+	a, err := m.Translate(1, addr, ProtWrite)
+	if err != nil {
+		t.Fatalf("process 1 should write to the segment. got=%v", err)
+	}
+
+	// Writing doesn't copy a shared page
+	if m.Write(1, m.Page(1, addr)) {
+		t.Errorf("a shared page shouldn't be copied on write")
+	}
+
+	b, err := m.Translate(2, addr, ProtRead)
+	if err != nil || a != b {
+		t.Errorf("both processes should see the same frame. want=%d, got=%d err=%v", a, b, err)
+	}
+
+	segments := m.SharedSegments()
+	if len(segments) != 1 || segments[0].Attached != 2 || segments[0].Size != 64 {
+		t.Errorf("wrong shared segments. got=%v", segments)
+	}
+}
+
+func TestSharedSegmentFreedOnLastDetach(t *testing.T) {
+	m := InitMemory(32, 256)
+
+	m.Add(32, 1)
+	m.SharedGet(0, 32)
+	m.Attach(1, 0)
+	m.Fork(1, 2)
+
+	touch(m, 1, SharedBase/m.PageSize)
+
+	if segments := m.SharedSegments(); segments[0].Attached != 2 {
+		t.Fatalf("the child should inherit the attachment. got=%v", segments)
+	}
+
+	if err := m.Detach(1, 0); err != nil {
+		t.Fatalf("process 1 should detach. got=%v", err)
+	}
+
+	if _, err := m.Translate(1, SharedBase, ProtRead); err == nil {
+		t.Errorf("process 1 shouldn't reach the segment after detaching")
+	}
+
+	if _, err := m.Translate(2, SharedBase, ProtRead); err != nil {
+		t.Errorf("the child should still reach the segment. got=%v", err)
+	}
+
+	m.RemovePages(2)
+
+	if segments := m.SharedSegments(); len(segments) != 0 {
+		t.Errorf("segment should be freed with the last process. got=%v", segments)
+	}
+
+	if physical, _ := m.Usage(); physical != 0 {
+		t.Errorf("the shared page should leave memory. got=%d", physical)
+	}
+
+	if err := m.Attach(1, 0); err != ErrNoSegment {
+		t.Errorf("a freed segment can't be attached. got=%v", err)
+	}
+}
+
+func TestSharedSegmentWithoutProcessesTakesNoMemory(t *testing.T) {
+	m := InitMemory(32, 256)
+	m.Add(32, 1)
+
+	if err := m.SharedGet(2, 64); err != nil {
+		t.Fatalf("segment should be made. got=%v", err)
+	}
+
+	if pages := len(m.pages); pages != 1 {
+		t.Errorf("nobody attached so the segment shouldn't have pages. got=%d pages", pages)
+	}
+
+	m.Attach(1, 2)
+	if pages := len(m.pages); pages != 3 {
+		t.Errorf("attaching should make the segment's pages. got=%d pages", pages)
+	}
+
+	m.RemovePages(1)
+	if pages := len(m.pages); pages != 0 {
+		t.Errorf("every page should be freed with the last process. got=%d pages", pages)
+	}
+}
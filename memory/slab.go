@@ -0,0 +1,156 @@
+package memory
+
+import "fmt"
+
+// MinSlabObjects : fewest objects a slab holds, slabs are made big enough for them
+const MinSlabObjects = 4
+
+// Cache : slab allocator for kernel objects of one size, each slab is a block from the buddy allocator
+//
+// Objects are handed out of slabs that already have objects in use before a
+// new slab is taken, and a slab goes back to the buddy allocator as soon as
+// its last object is freed.
+type Cache struct {
+	Name    string // What the objects are
+	Size    int    // Bytes in an object
+	Order   int    // Order of the block each slab takes
+	PerSlab int    // Objects in a slab
+
+	slabs    []*slab
+	failures int     // Allocations that couldn't get a slab
+	memory   *Memory // Memory the slabs are in, its lock guards the cache too
+}
+
+// slab : block of frames cut into objects
+type slab struct {
+	frame int   // First frame of its block
+	free  []int // Indexes of the objects nobody has
+}
+
+// AddCache : slab cache for kernel objects of size bytes, memory needs a buddy allocator for the slabs
+func (m *Memory) AddCache(name string, size int) (*Cache, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Buddy == nil {
+		return nil, fmt.Errorf("cache %q needs a buddy allocator for its slabs", name)
+	}
+
+	if size <= 0 {
+		return nil, fmt.Errorf("cache %q objects must be above zero bytes. size=%d", name, size)
+	}
+
+	order := m.Buddy.Order(size * MinSlabObjects)
+	if order > m.Buddy.MaxOrder {
+		return nil, fmt.Errorf("cache %q objects don't fit in memory. size=%d", name, size)
+	}
+
+	c := &Cache{
+		Name:    name,
+		Size:    size,
+		Order:   order,
+		PerSlab: (1 << order) * m.PageSize / size,
+		slabs:   []*slab{},
+		memory:  m,
+	}
+
+	m.caches = append(m.caches, c)
+
+	return c, nil
+}
+
+// Cache : slab cache with that name, nil if there isn't one
+func (m *Memory) Cache(name string) *Cache {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.caches {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// Caches : every slab cache, in the order they were added
+func (m *Memory) Caches() []*Cache {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*Cache{}, m.caches...)
+}
+
+// Alloc : kernel address of a free object, ErrNoMemory if a new slab was needed and there's no block for it
+func (c *Cache) Alloc() (int, error) {
+	m := c.memory
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var s *slab
+	for _, candidate := range c.slabs {
+		if len(candidate.free) > 0 {
+			s = candidate
+			break
+		}
+	}
+
+	if s == nil {
+		frame, err := m.Buddy.alloc(c.Order)
+		if err != nil {
+			c.failures++
+			return -1, err
+		}
+
+		s = &slab{frame: frame, free: make([]int, c.PerSlab)}
+		for i := range s.free {
+			s.free[i] = c.PerSlab - 1 - i
+		}
+
+		c.slabs = append(c.slabs, s)
+	}
+
+	i := s.free[len(s.free)-1]
+	s.free = s.free[:len(s.free)-1]
+
+	return s.frame*m.PageSize + i*c.Size, nil
+}
+
+// Free : give back an object from Alloc, its slab goes back to the buddy allocator once it's empty
+func (c *Cache) Free(addr int) {
+	m := c.memory
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, s := range c.slabs {
+		start := s.frame * m.PageSize
+		if addr < start || addr >= start+c.PerSlab*c.Size {
+			continue
+		}
+
+		s.free = append(s.free, (addr-start)/c.Size)
+
+		if len(s.free) == c.PerSlab {
+			m.Buddy.release(s.frame)
+			c.slabs = append(c.slabs[:i], c.slabs[i+1:]...)
+		}
+
+		return
+	}
+}
+
+// Stats : objects in use, objects the slabs have room for and allocations that failed
+func (c *Cache) Stats() (int, int, int) {
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+
+	capacity, free := 0, 0
+	for _, s := range c.slabs {
+		capacity += c.PerSlab
+		free += len(s.free)
+	}
+
+	return capacity - free, capacity, c.failures
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+)
+
+// Swap : backing store for pages that aren't in physical memory, kept in a file on disk
+//
+// The file is split into slots of one page each. A page gets a slot the first
+// time it's written out and keeps it until it's freed, so a page that wasn't
+// written since it was read back in doesn't have to be written out again.
+type Swap struct {
+	Path string // Swap file
+
+	file  *os.File
+	free  []int // Slots that were freed and can be reused
+	slots int   // Number of slots in the file
+	ins   int   // Pages read back in
+	outs  int   // Pages written out
+	size  int   // Bytes in a slot
+}
+
+// AddSwap : keep pages that leave physical memory in a swap file at path, the file is truncated
+func (m *Memory) AddSwap(path string) (*Swap, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	sw := &Swap{
+		Path: path,
+		file: file,
+		free: []int{},
+		size: m.PageSize,
+	}
+
+	m.mu.Lock()
+	m.Swap = sw
+	m.mu.Unlock()
+
+	return sw, nil
+}
+
+// Close : close the swap file
+func (sw *Swap) Close() error {
+	return sw.file.Close()
+}
+
+// alloc : slot for a page, reusing freed slots before growing the file
+func (sw *Swap) alloc() int {
+	if len(sw.free) > 0 {
+		slot := sw.free[len(sw.free)-1]
+		sw.free = sw.free[:len(sw.free)-1]
+		return slot
+	}
+
+	sw.slots++
+
+	return sw.slots - 1
+}
+
+// release : give a slot back
+func (sw *Swap) release(slot int) {
+	sw.free = append(sw.free, slot)
+}
+
+// write : write a page's contents to its slot, padded to the size of a slot
+func (sw *Swap) write(slot int, contents []byte) error {
+	buf := make([]byte, sw.size)
+	copy(buf, contents)
+
+	if _, err := sw.file.WriteAt(buf, int64(slot*sw.size)); err != nil {
+		return fmt.Errorf("swap out to slot %d: %v", slot, err)
+	}
+
+	sw.outs++
+
+	return nil
+}
+
+// read : read the contents of a slot
+func (sw *Swap) read(slot int) ([]byte, error) {
+	buf, err := sw.peek(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	sw.ins++
+
+	return buf, nil
+}
+
+// peek : read the contents of a slot without counting it as a page read back in
+func (sw *Swap) peek(slot int) ([]byte, error) {
+	buf := make([]byte, sw.size)
+
+	if _, err := sw.file.ReadAt(buf, int64(slot*sw.size)); err != nil {
+		return nil, fmt.Errorf("swap in from slot %d: %v", slot, err)
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSwapWritesOnlyDirtyPages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Room for one page
+	m := InitMemory(4, 4)
+	m.Add(8, 1)
+
+	sw, err := m.AddSwap(filepath.Join(dir, "swap"))
+	if err != nil {
+		t.Fatalf("swap: %v", err)
+	}
+	defer sw.Close()
+
+	m.PageIn(1, 0)
+	m.Write(1, 0)
+	m.tables[1][0].page.contents = []byte{1, 2, 3, 4}
+
+	// Evicts the dirty page 0
+	m.PageIn(1, 1)
+
+	page := m.tables[1][0].page
+	if ins, outs := m.SwapStats(); ins != 0 || outs != 1 || page.slot != 0 || page.contents != nil {
+		t.Fatalf("dirty page should be written out. ins=%d outs=%d slot=%d", ins, outs, page.slot)
+	}
+
+	// Evicts the clean page 1, which has nothing to write
+	m.PageIn(1, 0)
+
+	if ins, outs := m.SwapStats(); ins != 1 || outs != 1 {
+		t.Errorf("page 0 should be read back and page 1 dropped. ins=%d outs=%d", ins, outs)
+	}
+
+	if string(page.contents) != string([]byte{1, 2, 3, 4}) {
+		t.Errorf("contents should survive the swap. got=%v", page.contents)
+	}
+
+	// Page 0 is clean now, evicting it again doesn't write
+	m.PageIn(1, 1)
+
+	if _, outs := m.SwapStats(); outs != 1 {
+		t.Errorf("clean page shouldn't be written again. outs=%d", outs)
+	}
+
+	m.RemovePages(1)
+
+	if len(sw.free) != 1 || sw.alloc() != 0 {
+		t.Errorf("slot should be freed and reused")
+	}
+}
@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+const (
+
+	// DefaultLookupTime : nanoseconds to look up a TLB entry
+	DefaultLookupTime = 1
+
+	// DefaultAccessTime : nanoseconds for a memory access
+	DefaultAccessTime = 100
+)
+
+// TLB : translation lookaside buffer caching page table entries for one CPU
+//
+// Entries are split into sets of Ways entries each and a virtual page can only
+// be cached in the set picked by its page number. Without ASIDs the TLB only
+// holds entries of the process on the CPU and is flushed on every context
+// switch, with them every entry is tagged with the process it belongs to.
+type TLB struct {
+	Sets        int     // Number of sets
+	Ways        int     // Entries in each set, 1 is direct mapped and Sets 1 is fully associative
+	Replacement string  // Entry to replace in a full set: lru || fifo || random
+	ASID        bool    // Tag entries with the process instead of flushing on a context switch
+	LookupTime  float64 // Nanoseconds to look an entry up, for the effective access time
+
+	sets    [][]tlbEntry
+	current int // Process the TLB was last switched to
+	clock   int // Stamps entries for replacement
+	hits    int
+	misses  int
+	flushes int
+	memory  *Memory // Memory the TLB is in front of, its lock guards the TLB too
+}
+
+// tlbEntry : cached translation of a virtual page of a process
+type tlbEntry struct {
+	valid bool
+	asid  int  // Process the entry belongs to
+	vpn   int  // Virtual page number
+	frame int  // Frame holding the page
+	pte   *PTE // Page table entry it caches, for its protection and reference bits
+	stamp int  // When the entry was loaded or last used, depending on Replacement
+}
+
+// AddTLB : put a TLB with that many entries in front of memory, entries must be a multiple of ways
+func (m *Memory) AddTLB(entries int, ways int, replacement string, asid bool) (*TLB, error) {
+
+	if entries <= 0 || ways <= 0 || entries%ways != 0 {
+		return nil, fmt.Errorf("tlb entries must be a positive multiple of its ways. entries=%d ways=%d", entries, ways)
+	}
+
+	switch replacement {
+	case "lru", "fifo", "random":
+	default:
+		return nil, fmt.Errorf("unknown tlb replacement %q", replacement)
+	}
+
+	t := &TLB{
+		Sets:        entries / ways,
+		Ways:        ways,
+		Replacement: replacement,
+		ASID:        asid,
+		LookupTime:  DefaultLookupTime,
+		sets:        make([][]tlbEntry, entries/ways),
+		current:     -1,
+		memory:      m,
+	}
+
+	for i := range t.sets {
+		t.sets[i] = make([]tlbEntry, ways)
+	}
+
+	m.mu.Lock()
+	m.tlbs = append(m.tlbs, t)
+	m.mu.Unlock()
+
+	return t, nil
+}
+
+// Switch : context switch to a process, flushes the TLB unless entries are tagged
+func (t *TLB) Switch(pid int) {
+	t.memory.mu.Lock()
+	defer t.memory.mu.Unlock()
+
+	if pid == t.current {
+		return
+	}
+
+	t.current = pid
+
+	if !t.ASID {
+		t.flush()
+	}
+}
+
+// flush : invalidate every entry, the memory lock must be held
+func (t *TLB) flush() {
+	for _, set := range t.sets {
+		for i := range set {
+			set[i].valid = false
+		}
+	}
+
+	t.flushes++
+}
+
+// invalidate : drop the entry caching a page table entry, the memory lock must be held
+func (t *TLB) invalidate(pte *PTE) {
+	for _, set := range t.sets {
+		for i := range set {
+			if set[i].valid && set[i].pte == pte {
+				set[i].valid = false
+			}
+		}
+	}
+}
+
+// Translate : physical address of a virtual address of a process, walking the page table on a miss
+//
+// Errors are the same as Memory.Translate.
+func (t *TLB) Translate(pid int, vaddr int, access int) (int, error) {
+	m := t.memory
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Nothing to cache without page tables
+	if m.Contiguous != nil {
+		return m.translate(pid, vaddr, access)
+	}
+
+	// The TLB caches pages, so segments are checked before it
+	if m.Segmented && vaddr < SharedBase {
+		linear, err := m.segment(pid, vaddr, access)
+		if err != nil {
+			return linear, err
+		}
+
+		vaddr = linear
+	}
+
+	if vaddr < 0 {
+		return -1, ErrBadAddress
+	}
+
+	vpn := vaddr / m.PageSize
+	set := t.sets[vpn%t.Sets]
+
+	t.clock++
+
+	for i := range set {
+		e := &set[i]
+		if !e.valid || e.asid != pid || e.vpn != vpn {
+			continue
+		}
+
+		if e.pte.Protection&access == 0 {
+			return -1, ErrProtection
+		}
+
+		t.hits++
+
+		if t.Replacement == "lru" {
+			e.stamp = t.clock
+		}
+
+		m.reference(pid, e.pte)
+
+		return e.frame*m.PageSize + vaddr%m.PageSize, nil
+	}
+
+	t.misses++
+
+	addr, err := m.walk(pid, vaddr, access)
+	if err != nil {
+		return addr, err
+	}
+
+	// Cache the translation in a free entry of the set or replace one
+	victim := -1
+	for i := range set {
+		if !set[i].valid {
+			victim = i
+			break
+		}
+	}
+
+	if victim == -1 && t.Replacement == "random" {
+		victim = rand.Intn(len(set))
+	} else if victim == -1 {
+		victim = 0
+		for i := range set {
+			if set[i].stamp < set[victim].stamp {
+				victim = i
+			}
+		}
+	}
+
+	pte := m.entry(pid, vpn)
+	set[victim] = tlbEntry{
+		valid: true,
+		asid:  pid,
+		vpn:   vpn,
+		frame: pte.Frame,
+		pte:   pte,
+		stamp: t.clock,
+	}
+
+	return addr, nil
+}
+
+// HitRate : fraction of translations found in the TLB
+func (t *TLB) HitRate() float64 {
+	t.memory.mu.Lock()
+	defer t.memory.mu.Unlock()
+
+	if t.hits+t.misses == 0 {
+		return 0
+	}
+
+	return float64(t.hits) / float64(t.hits+t.misses)
+}
+
+// Stats : hits, misses and flushes so far
+func (t *TLB) Stats() (int, int, int) {
+	t.memory.mu.Lock()
+	defer t.memory.mu.Unlock()
+
+	return t.hits, t.misses, t.flushes
+}
+
+// EffectiveAccessTime : average nanoseconds for a memory access through the TLB,
+// a miss costs one more memory access to walk the page table
+func (t *TLB) EffectiveAccessTime() float64 {
+	h := t.HitRate()
+
+	return h*(t.LookupTime+t.memory.AccessTime) + (1-h)*(t.LookupTime+2*t.memory.AccessTime)
+}
@@ -0,0 +1,86 @@
+package memory
+
+import "testing"
+
+func TestTLBCachesTranslations(t *testing.T) {
+	m := InitMemory(32, 4096)
+	m.Add(64, 1)
+	m.Add(64, 2)
+
+	for _, pid := range []int{1, 2} {
+		m.PageIn(pid, 0)
+		m.PageIn(pid, 1)
+	}
+
+	tests := []struct {
+		asid    bool
+		hits    int
+		flushes int
+	}{
+		// Process 1's entries are flushed when process 2 runs
+		{false, 1, 3},
+		{true, 2, 0},
+	}
+
+	for _, tt := range tests {
+		tlb, err := m.AddTLB(4, 2, "lru", tt.asid)
+		if err != nil {
+			t.Fatalf("tlb: %v", err)
+		}
+
+		tlb.Switch(1)
+		want, _ := m.Translate(1, 40, ProtRead)
+
+		if addr, err := tlb.Translate(1, 40, ProtRead); err != nil || addr != want {
+			t.Fatalf("wrong translation. want=%d, got=%d (%v)", want, addr, err)
+		}
+
+		tlb.Translate(1, 41, ProtRead)
+
+		tlb.Switch(2)
+		tlb.Translate(2, 40, ProtRead)
+
+		tlb.Switch(1)
+		tlb.Translate(1, 40, ProtRead)
+
+		if hits, _, flushes := tlb.Stats(); hits != tt.hits || flushes != tt.flushes {
+			t.Errorf("asid=%t: wrong stats. hits=%d flushes=%d", tt.asid, hits, flushes)
+		}
+	}
+
+	if _, err := m.AddTLB(6, 4, "lru", true); err == nil {
+		t.Errorf("entries that aren't a multiple of the ways should be an error")
+	}
+}
+
+func TestTLBShootdownOnEviction(t *testing.T) {
+	// Room for one page
+	m := InitMemory(32, 32)
+	m.Add(64, 1)
+
+	tlb, _ := m.AddTLB(4, 4, "fifo", true)
+
+	m.PageIn(1, 0)
+	tlb.Translate(1, 0, ProtRead)
+
+	// Evicts page 0
+	m.PageIn(1, 1)
+
+	if _, err := tlb.Translate(1, 0, ProtRead); err != ErrPageFault {
+		t.Fatalf("evicted page shouldn't be found in the TLB. got=%v", err)
+	}
+
+	if hits, misses, _ := tlb.Stats(); hits != 0 || misses != 2 {
+		t.Errorf("wrong stats. hits=%d misses=%d", hits, misses)
+	}
+
+	// Half the accesses hit and a miss walks the page table too
+	m.PageIn(1, 0)
+	tlb.Translate(1, 0, ProtRead)
+	tlb.Translate(1, 0, ProtRead)
+	tlb.Translate(1, 0, ProtRead)
+
+	if eat := tlb.EffectiveAccessTime(); eat != 0.4*101+0.6*201 {
+		t.Errorf("wrong effective access time. got=%f", eat)
+	}
+}
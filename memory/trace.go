@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Trace : every page reference of a run written to a file, so optimal replacement can replay it in a later run
+type Trace struct {
+	Path string // Trace file
+
+	file *os.File
+	w    *bufio.Writer
+	mu   sync.Mutex // Closing races with the CPUs still touching pages
+}
+
+// AddTrace : write every page referenced from now on to a trace file at path, the file is truncated
+func (m *Memory) AddTrace(path string) (*Trace, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Trace{
+		Path: path,
+		file: file,
+		w:    bufio.NewWriter(file),
+	}
+
+	m.mu.Lock()
+	m.Trace = t
+	m.mu.Unlock()
+
+	return t, nil
+}
+
+// write : add a page to the trace, nothing is written once it's closed
+func (t *Trace) write(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.w != nil {
+		fmt.Fprintln(t.w, id)
+	}
+}
+
+// Close : flush the trace and close the file
+func (t *Trace) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.w == nil {
+		return nil
+	}
+
+	err := t.w.Flush()
+	t.w = nil
+
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// ReadTrace : page references recorded in a trace file, whitespace separated page IDs
+func ReadTrace(path string) ([]int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+
+	refs := make([]int, len(fields))
+	for i, field := range fields {
+		if refs[i], err = strconv.Atoi(field); err != nil {
+			return nil, fmt.Errorf("trace %s: bad page reference %q", path, field)
+		}
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTraceRecordsReferencesForOptimal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := InitMemory(4, 8)
+	m.Add(16, 1)
+
+	trace, err := m.AddTrace(filepath.Join(dir, "trace"))
+	if err != nil {
+		t.Fatalf("trace: %v", err)
+	}
+
+	for _, vpn := range []int{0, 1, 2, 0, 1, 3, 0} {
+		touch(m, 1, vpn)
+	}
+
+	if err := trace.Close(); err != nil {
+		t.Fatalf("trace should close. got=%v", err)
+	}
+
+	refs, err := ReadTrace(trace.Path)
+	if err != nil {
+		t.Fatalf("trace should be read back. got=%v", err)
+	}
+
+	if want := m.References(); !reflect.DeepEqual(refs, want) {
+		t.Fatalf("trace should hold every reference. want=%v, got=%v", want, refs)
+	}
+
+	// Replaying the trace never does worse than the policy that recorded it
+	optimal, _ := NewReplacementPolicy("optimal", refs)
+	if faults, fifo := Replay(optimal, refs, 2), Replay(NewFIFO(), refs, 2); faults > fifo {
+		t.Errorf("optimal shouldn't fault more than fifo. optimal=%d fifo=%d", faults, fifo)
+	}
+
+	ioutil.WriteFile(trace.Path, []byte("1 2 x"), 0600)
+	if _, err := ReadTrace(trace.Path); err == nil {
+		t.Errorf("a trace with something other than page IDs should fail")
+	}
+}
@@ -0,0 +1,77 @@
+package memory
+
+const (
+
+	// DefaultWindow : working set window, in ticks
+	DefaultWindow = 100
+)
+
+// Tick : advance simulated time by one tick, the schedulers tick once for every instruction they run
+func (m *Memory) Tick() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ticks++
+}
+
+// stamp : count a process touching a page and remember the tick it did, the lock must be held
+func (m *Memory) stamp(pid int, entry *PTE) {
+	m.clocks[pid]++
+	entry.used = m.ticks
+}
+
+// WorkingSet : number of pages a process touched in the last Window ticks
+//
+// Time passes whether the process runs or not, so a process that's kept off
+// the CPU long enough has nothing left in its working set.
+func (m *Memory) WorkingSet(pid int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.ticks
+
+	size := 0
+	for _, e := range m.tables[pid] {
+		if e.used > 0 && e.used > now-m.Window {
+			size++
+		}
+	}
+
+	return size
+}
+
+// ProcessStats : memory references and page faults of a process so far
+func (m *Memory) ProcessStats(pid int) (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.clocks[pid], m.procFaults[pid]
+}
+
+// Frames : number of frames in physical memory left for user pages
+func (m *Memory) Frames() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Buddy == nil {
+		return len(m.PhysicalMemory)
+	}
+
+	return len(m.PhysicalMemory) - m.Buddy.frames
+}
+
+// SwapOut : move every page of a process that nobody else shares out of physical memory, return how many
+func (m *Memory) SwapOut(pid int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := 0
+	for _, e := range m.tables[pid] {
+		if e.Valid && e.page.refs == 1 {
+			m.evict(e.page)
+			out++
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,74 @@
+package memory
+
+import "testing"
+
+// touch reads a virtual page of a process, paging it in if it faults
+func touch(m *Memory, pid int, vpn int) {
+	if _, err := m.Translate(pid, vpn*m.PageSize, ProtRead); err == ErrPageFault {
+		m.PageIn(pid, vpn)
+	}
+}
+
+func TestWorkingSetSlidesWithTicks(t *testing.T) {
+	m := InitMemory(32, 256)
+	m.Window = 3
+
+	m.Add(128, 1)
+
+	// One page touched every tick
+	for _, vpn := range []int{0, 1, 2, 3} {
+		m.Tick()
+		touch(m, 1, vpn)
+	}
+
+	if ws := m.WorkingSet(1); ws != 3 {
+		t.Errorf("last 3 ticks touched 3 pages. got=%d", ws)
+	}
+
+	for i := 0; i < 3; i++ {
+		m.Tick()
+		touch(m, 1, 0)
+	}
+
+	if ws := m.WorkingSet(1); ws != 1 {
+		t.Errorf("last 3 ticks only touched page 0. got=%d", ws)
+	}
+
+	if refs, faults := m.ProcessStats(1); refs != 7 || faults != 4 {
+		t.Errorf("wrong stats. refs=%d faults=%d", refs, faults)
+	}
+
+	// Time passes while the process is off the CPU
+	for i := 0; i < 3; i++ {
+		m.Tick()
+	}
+
+	if ws := m.WorkingSet(1); ws != 0 {
+		t.Errorf("a process that didn't run for a whole window has no working set. got=%d", ws)
+	}
+}
+
+func TestSwapOutKeepsSharedPages(t *testing.T) {
+	m := InitMemory(32, 256)
+
+	m.Add(64, 1)
+	m.Add(64, 2)
+	m.Fork(2, 3)
+
+	for _, pid := range []int{1, 2} {
+		touch(m, pid, 0)
+		touch(m, pid, 1)
+	}
+
+	if out := m.SwapOut(1); out != 2 {
+		t.Errorf("both pages of process 1 should go. got=%d", out)
+	}
+
+	if out := m.SwapOut(2); out != 0 {
+		t.Errorf("process 3 still uses the pages of process 2. got=%d", out)
+	}
+
+	if physical, _ := m.Usage(); physical != 2 {
+		t.Errorf("only the shared pages should be left in memory. got=%d", physical)
+	}
+}
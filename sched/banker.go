@@ -0,0 +1,167 @@
+package sched
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LockKey : key for the kernel lock in resource claims, semaphores use their own keys
+	LockKey = -1
+)
+
+// ParseClaims : read the most of each resource a program will hold at once from a template's
+// Resources line, e.g. `Resources: lock:1 3:2` claims the kernel lock and 2 units of semaphore 3
+func ParseClaims(fields []string) (map[int]int, error) {
+	claims := make(map[int]int, len(fields))
+
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+
+		parts := strings.Split(field, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("resource claim %q should look like key:units", field)
+		}
+
+		key := LockKey
+		if parts[0] != "lock" {
+			id, err := strconv.Atoi(parts[0])
+			if err != nil || id < 0 {
+				return nil, fmt.Errorf("resource claim %q has a bad semaphore key", field)
+			}
+
+			key = id
+		}
+
+		units, err := strconv.Atoi(parts[1])
+		if err != nil || units < 0 {
+			return nil, fmt.Errorf("resource claim %q has a bad number of units", field)
+		}
+
+		claims[key] = units
+	}
+
+	return claims, nil
+}
+
+// available units of a resource nobody holds, the kernel lock must be held
+func (k *Kernel) available(key int) int {
+	if key == LockKey {
+		if _, locked := k.Lock.Owner(); locked {
+			return 0
+		}
+
+		return 1
+	}
+
+	if sem, ok := k.semaphores[key]; ok {
+		return sem.count
+	}
+
+	return 0
+}
+
+// allocated units of a resource the process holds, the kernel lock must be held
+func (k *Kernel) allocated(p *Process, key int) int {
+	if key == LockKey {
+		if owner, locked := k.Lock.Owner(); locked && owner == p.PID {
+			return 1
+		}
+
+		return 0
+	}
+
+	if sem, ok := k.semaphores[key]; ok {
+		return sem.holders[p.PID]
+	}
+
+	return 0
+}
+
+// overClaims checks if taking another unit of a resource would go past what the process declared
+func (k *Kernel) overClaims(p *Process, key int) bool {
+	if !k.Avoidance || p.claims == nil {
+		return false
+	}
+
+	return k.allocated(p, key)+1 > p.claims[key]
+}
+
+// safe runs the Banker's safety check as if the process was given a unit of the resource,
+// the kernel lock must be held
+//
+// Processes that didn't declare their claims aren't checked and what they hold
+// just isn't available.
+func (k *Kernel) safe(p *Process, key int) bool {
+	if !k.Avoidance || p.claims == nil {
+		return true
+	}
+
+	// Everyone with claims and everything they claim
+	procs := []*Process{}
+	keys := map[int]bool{key: true}
+
+	// Zombies gave back everything and won't ask for more
+	for _, q := range k.procs {
+		if q.claims == nil || q.zombie {
+			continue
+		}
+
+		procs = append(procs, q)
+		for claimed := range q.claims {
+			keys[claimed] = true
+		}
+	}
+
+	// Pretend the process got the unit
+	alloc := func(q *Process, r int) int {
+		units := k.allocated(q, r)
+		if q == p && r == key {
+			units++
+		}
+
+		return units
+	}
+
+	work := make(map[int]int, len(keys))
+	for r := range keys {
+		work[r] = k.available(r)
+	}
+	work[key]--
+
+	// Let everyone whose remaining claims fit finish and give back what they hold
+	finished := make(map[*Process]bool, len(procs))
+	for progress := true; progress; {
+		progress = false
+
+		for _, q := range procs {
+			if finished[q] {
+				continue
+			}
+
+			fits := true
+			for r, units := range q.claims {
+				if units-alloc(q, r) > work[r] {
+					fits = false
+					break
+				}
+			}
+
+			if !fits {
+				continue
+			}
+
+			for r := range keys {
+				work[r] += alloc(q, r)
+			}
+
+			finished[q] = true
+			progress = true
+		}
+	}
+
+	return len(finished) == len(procs)
+}
@@ -0,0 +1,83 @@
+package sched
+
+import (
+	"testing"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+)
+
+func TestParseClaims(t *testing.T) {
+	claims, err := ParseClaims([]string{"lock:1", "3:2"})
+	if err != nil {
+		t.Fatalf("claims should parse. got=%v", err)
+	}
+
+	if len(claims) != 2 || claims[LockKey] != 1 || claims[3] != 2 {
+		t.Errorf("wrong claims. got=%v", claims)
+	}
+
+	for _, bad := range []string{"lock", "x:1", "3:-1"} {
+		if _, err := ParseClaims([]string{bad}); err == nil {
+			t.Errorf("claim %q shouldn't parse", bad)
+		}
+	}
+}
+
+func TestAvoidanceKeepsUnsafeRequestWaiting(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	k.Avoidance = true
+
+	k.semaphores[1] = InitSemaphore(k, 1, 1)
+	k.semaphores[2] = InitSemaphore(k, 2, 1)
+
+	first := newTestProcess(k,
+		code.Make(code.WAIT, 1), code.Make(code.WAIT, 2),
+		code.Make(code.SIGNAL, 2), code.Make(code.SIGNAL, 1),
+	)
+	second := newTestProcess(k,
+		code.Make(code.WAIT, 2), code.Make(code.WAIT, 1),
+	)
+	first.claims = map[int]int{1: 1, 2: 1}
+	second.claims = map[int]int{1: 1, 2: 1}
+
+	if err := first.Execute(s); err != nil {
+		t.Fatalf("first WAIT is safe. got=%v", err)
+	}
+
+	// Semaphore 2 is free but giving it away could deadlock
+	block(t, s, second)
+
+	if k.semaphores[2].Count() != 1 {
+		t.Fatalf("unsafe request shouldn't get the unit")
+	}
+
+	if err := first.Execute(s); err != nil {
+		t.Fatalf("first process can finish so its request is safe. got=%v", err)
+	}
+
+	// First process can still ask for semaphore 2 until it gives back both
+	for i := 0; i < 2; i++ {
+		if err := first.Execute(s); err != nil {
+			t.Fatalf("SIGNAL shouldn't fail. got=%v", err)
+		}
+	}
+
+	if second.State != READY || second.ip != 2 {
+		t.Errorf("second process should get semaphore 2 once it's safe. ip=%d", second.ip)
+	}
+}
+
+func TestAvoidanceRejectsOverClaim(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	k.Avoidance = true
+
+	p := newTestProcess(k, code.Make(code.ENTER))
+	p.claims = map[int]int{}
+
+	if err := p.Execute(s); err == nil || err == ErrBlocked {
+		t.Errorf("ENTER without claiming the lock should end the process. got=%v", err)
+	}
+}
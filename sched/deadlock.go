@@ -0,0 +1,342 @@
+package sched
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+)
+
+const (
+
+	// Deadlock recovery policies
+
+	// RecoverNone : only report deadlocks
+	RecoverNone = "none"
+
+	// RecoverKill : terminate the youngest process in each deadlock
+	RecoverKill = "kill"
+
+	// RecoverRollback : take back the resources of the youngest process in each deadlock and restart it
+	RecoverRollback = "rollback"
+)
+
+// Deadlock : cycle of processes each waiting on something only the next can give it
+//
+// A single process means nobody left is ever going to wake it up.
+type Deadlock struct {
+	PIDs      []int    // Processes in the cycle, each waits on the next and the last on the first
+	Resources []string // What each process is waiting on
+}
+
+// String : string representation of deadlock
+func (d Deadlock) String() string {
+	steps := make([]string, len(d.PIDs))
+	for i, pid := range d.PIDs {
+		steps[i] = fmt.Sprintf("%d (%s)", pid, d.Resources[i])
+	}
+
+	if len(d.PIDs) == 1 {
+		return fmt.Sprintf("%s waits forever", steps[0])
+	}
+
+	return fmt.Sprintf("%s -> %d", strings.Join(steps, " -> "), d.PIDs[0])
+}
+
+// Detector : periodically looks for processes that are blocked for good
+//
+// The wait-for graph has an edge from a blocked process to every process that
+// could still wake it up: the owner of the kernel lock, or a process with a
+// SIGNAL, CSIGNAL, SEND or RECV on the same key left in its program. Blocked
+// processes that can't reach a process that's able to run are deadlocked.
+type Detector struct {
+	Interval time.Duration // Time between checks
+	Recovery string        // What to do about a deadlock
+
+	deadlocks []Deadlock      // Deadlocks found by the last check
+	reported  map[string]bool // Deadlocks already in the event log
+	kernel    *Kernel
+	mu        sync.Mutex // Guards the deadlocks
+}
+
+// InitDetector : create new deadlock detector
+func InitDetector(k *Kernel, interval time.Duration, recovery string) *Detector {
+	return &Detector{
+		Interval:  interval,
+		Recovery:  recovery,
+		deadlocks: []Deadlock{},
+		reported:  make(map[string]bool),
+		kernel:    k,
+	}
+}
+
+// Run : check for deadlocks until the kernel stops
+func (d *Detector) Run() {
+
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.check()
+		case <-d.kernel.quit:
+			return
+		}
+	}
+}
+
+// Deadlocks : deadlocks found by the last check
+func (d *Detector) Deadlocks() []Deadlock {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Deadlock{}, d.deadlocks...)
+}
+
+// check finds deadlocks, reports the new ones and recovers from them
+func (d *Detector) check() []Deadlock {
+	k := d.kernel
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	deadlocks := k.deadlocks()
+
+	reported := make(map[string]bool, len(deadlocks))
+	for _, deadlock := range deadlocks {
+		s := deadlock.String()
+
+		if !d.reported[s] {
+			k.logEvent("deadlock: %s", s)
+		}
+
+		reported[s] = true
+	}
+
+	d.mu.Lock()
+	d.deadlocks = deadlocks
+	d.reported = reported
+	d.mu.Unlock()
+
+	if d.Recovery == RecoverKill || d.Recovery == RecoverRollback {
+		for _, deadlock := range deadlocks {
+			d.recover(deadlock)
+		}
+	}
+
+	return deadlocks
+}
+
+// recover breaks a deadlock by picking on its youngest process, the kernel lock must be held
+func (d *Detector) recover(deadlock Deadlock) {
+	k := d.kernel
+
+	youngest := deadlock.PIDs[0]
+	for _, pid := range deadlock.PIDs {
+		if pid > youngest {
+			youngest = pid
+		}
+	}
+
+	victim, ok := k.procs[youngest]
+	if !ok || victim.waitingOn == nil {
+		// Already picked for another deadlock it was part of
+		return
+	}
+
+	victim.waitingOn.(waitable).cancel(victim)
+	k.releaseHeld(victim)
+
+	switch d.Recovery {
+	case RecoverKill:
+		k.logEvent("deadlock recovery: killed process %d", victim.PID)
+		atomic.StoreInt32(&victim.killed, 1)
+	case RecoverRollback:
+		k.logEvent("deadlock recovery: rolled back process %d", victim.PID)
+		victim.rollback()
+	}
+
+	k.wake(victim)
+}
+
+// deadlocks builds the wait-for graph and finds its cycles, the kernel lock must be held
+func (k *Kernel) deadlocks() []Deadlock {
+
+	// Everyone waiting in line for something
+	waiting := make(map[*Process]waitable)
+
+	for _, w := range k.waitables() {
+		for _, p := range w.waiting() {
+			waiting[p] = w
+		}
+	}
+
+	// Programs of processes that are running can only be read where they
+	// never change, so assume they could still do anything in them
+	from := func(q *Process) int {
+		if _, ok := waiting[q]; ok {
+			return q.ip
+		}
+
+		return 0
+	}
+
+	// Whittle down to the processes that can't be woken up by someone who isn't stuck,
+	// zombies are done running and can't wake anyone
+	stuck := make(map[*Process]waitable, len(waiting))
+	for p, w := range waiting {
+		stuck[p] = w
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for p, w := range stuck {
+			for _, q := range k.procs {
+				if _, qStuck := stuck[q]; q == p || q.zombie || qStuck || !w.wakes(p, q, from(q)) {
+					continue
+				}
+
+				delete(stuck, p)
+				changed = true
+				break
+			}
+		}
+	}
+
+	// Follow the edges between stuck processes to find the cycles
+	procs := make([]*Process, 0, len(stuck))
+	for p := range stuck {
+		procs = append(procs, p)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+
+	next := func(p *Process) *Process {
+		for _, q := range procs {
+			if q != p && stuck[p].wakes(p, q, q.ip) {
+				return q
+			}
+		}
+
+		return nil
+	}
+
+	deadlocks := []Deadlock{}
+	visited := make(map[*Process]bool, len(procs))
+
+	for _, start := range procs {
+		path := []*Process{}
+		onPath := make(map[*Process]int)
+
+		for p := start; p != nil && !visited[p]; {
+			if i, ok := onPath[p]; ok {
+				deadlocks = append(deadlocks, newDeadlock(path[i:], stuck))
+				break
+			}
+
+			onPath[p] = len(path)
+			path = append(path, p)
+
+			q := next(p)
+			if q == nil {
+				deadlocks = append(deadlocks, newDeadlock([]*Process{p}, stuck))
+			}
+
+			p = q
+		}
+
+		for _, p := range path {
+			visited[p] = true
+		}
+	}
+
+	return deadlocks
+}
+
+// waitables : everything processes can wait on forever, the kernel lock must be held
+func (k *Kernel) waitables() []waitable {
+	waitables := []waitable{k.Lock, k.Children}
+
+	for _, sem := range k.semaphores {
+		waitables = append(waitables, sem)
+	}
+
+	for _, c := range k.conds {
+		waitables = append(waitables, c)
+	}
+
+	for _, mb := range k.Mailboxes {
+		waitables = append(waitables, mb)
+	}
+
+	for _, pp := range k.pipes {
+		waitables = append(waitables, pp)
+	}
+
+	return waitables
+}
+
+// newDeadlock describes a cycle in the wait-for graph
+func newDeadlock(cycle []*Process, waitingOn map[*Process]waitable) Deadlock {
+	d := Deadlock{
+		PIDs:      make([]int, len(cycle)),
+		Resources: make([]string, len(cycle)),
+	}
+
+	for i, p := range cycle {
+		d.PIDs[i] = p.PID
+		d.Resources[i] = waitingOn[p].String()
+	}
+
+	return d
+}
+
+// releaseHeld takes back the kernel lock and semaphore units a process has, the kernel lock must be held
+func (k *Kernel) releaseHeld(p *Process) {
+
+	if owner, locked := k.Lock.Owner(); locked && owner == p.PID {
+		k.Lock.release(p)
+	}
+
+	for _, sem := range k.semaphores {
+		for sem.holders[p.PID] > 0 {
+			sem.signal(p)
+		}
+	}
+}
+
+// mayDo checks if the program has the instruction left starting from from, a negative key matches any operand
+func (p *Process) mayDo(op code.Opcode, key int, from int) bool {
+	for ip := from; ip < len(p.ins); ip += code.Width(code.Opcode(p.ins[ip])) {
+		if code.Opcode(p.ins[ip]) != op {
+			continue
+		}
+
+		if key < 0 || int(p.ins[ip+1]) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rollback restarts the process from the program it was loaded with, at the instruction it started at
+func (p *Process) rollback() {
+	copy(p.ins, p.image)
+
+	p.ip = p.start
+	p.acc = 0
+	p.Runtime = 0
+	p.relock, p.signaled, p.parked = false, false, false
+
+	for ip := 0; ip < len(p.ins); ip += code.Width(code.Opcode(p.ins[ip])) {
+		if ip >= p.start && code.Opcode(p.ins[ip]) == code.CALC {
+			p.Runtime += int(p.ins[ip+1])
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package sched
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+)
+
+// newTestDeadlock makes two processes that take semaphores 1 and 2 in opposite orders and
+// runs them until each holds one and waits on the other
+func newTestDeadlock(t *testing.T) (*Kernel, *Scheduler, *Process, *Process) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	k.semaphores[1] = InitSemaphore(k, 1, 1)
+	k.semaphores[2] = InitSemaphore(k, 2, 1)
+
+	first := newTestProcess(k,
+		code.Make(code.WAIT, 1), code.Make(code.WAIT, 2),
+		code.Make(code.SIGNAL, 2), code.Make(code.SIGNAL, 1),
+	)
+	second := newTestProcess(k,
+		code.Make(code.WAIT, 2), code.Make(code.WAIT, 1),
+		code.Make(code.SIGNAL, 1), code.Make(code.SIGNAL, 2),
+	)
+
+	for _, p := range []*Process{first, second} {
+		if err := p.Execute(s); err != nil {
+			t.Fatalf("first WAIT shouldn't block. got=%v", err)
+		}
+	}
+
+	block(t, s, first)
+	block(t, s, second)
+
+	return k, s, first, second
+}
+
+func TestDetectDeadlock(t *testing.T) {
+	k, s, first, second := newTestDeadlock(t)
+
+	d := k.AddDetector(0, RecoverNone)
+	deadlocks := d.check()
+
+	if len(deadlocks) != 1 {
+		t.Fatalf("should find one deadlock. got=%v", deadlocks)
+	}
+
+	want := fmt.Sprintf("%d (semaphore 2) -> %d (semaphore 1) -> %d", first.PID, second.PID, first.PID)
+	if got := deadlocks[0].String(); got != want {
+		t.Errorf("wrong description. want=%q, got=%q", want, got)
+	}
+
+	if len(k.Events()) != 1 {
+		t.Errorf("deadlock should be logged once. got=%v", k.Events())
+	}
+
+	// Reported deadlocks aren't logged again
+	d.check()
+	if len(k.Events()) != 1 {
+		t.Errorf("deadlock should only be logged once. got=%v", k.Events())
+	}
+
+	// Someone who can still signal means there's no deadlock
+	k.register(CreateProcess("free", 0, 32, code.Make(code.SIGNAL, 1), 0, nil))
+	if deadlocks := d.check(); len(deadlocks) != 0 {
+		t.Errorf("running process could break the cycle. got=%v", deadlocks)
+	}
+
+	if len(s.ReadyQ) != 0 {
+		t.Errorf("detecting shouldn't wake anyone up. got=%d ready", len(s.ReadyQ))
+	}
+}
+
+func TestDeadlockRecovery(t *testing.T) {
+	tests := []struct {
+		recovery string
+		killed   bool
+		ip       int
+	}{
+		{RecoverKill, true, 2},
+		{RecoverRollback, false, 0},
+	}
+
+	for _, tt := range tests {
+		k, s, first, second := newTestDeadlock(t)
+
+		k.AddDetector(0, tt.recovery).check()
+
+		// The youngest gives up its unit so the other gets through
+		if first.State != READY || first.ip != 4 {
+			t.Errorf("%s: first process should get the semaphore. ip=%d", tt.recovery, first.ip)
+		}
+
+		if second.State != READY || second.isKilled() != tt.killed || second.ip != tt.ip {
+			t.Errorf("%s: youngest process picked wrong. killed=%t, ip=%d", tt.recovery, second.isKilled(), second.ip)
+		}
+
+		if len(s.ReadyQ) != 2 || len(k.semaphores[1].waiters) != 0 {
+			t.Errorf("%s: nobody should be waiting anymore", tt.recovery)
+		}
+	}
+}
+
+func TestExitedSignallerCantWakeWaiter(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	k.semaphores[1] = InitSemaphore(k, 1, 0)
+
+	// Child halts before it gets to its SIGNAL and stays a zombie until the parent waits
+	parent := newTestProcess(k, code.Make(code.CALC, 5))
+	child := newTestChild(k, parent, code.Make(code.HALT, 0), code.Make(code.SIGNAL, 1))
+	waiter := newTestProcess(k, code.Make(code.WAIT, 1))
+
+	child.Execute(s)
+	s.exit(child)
+
+	if !child.zombie {
+		t.Fatalf("child should be a zombie until it's reaped")
+	}
+
+	block(t, s, waiter)
+
+	d := k.AddDetector(0, RecoverNone)
+	if deadlocks := d.check(); len(deadlocks) != 1 || deadlocks[0].PIDs[0] != waiter.PID {
+		t.Errorf("nobody left can signal the waiter. got=%v", deadlocks)
+	}
+}
+
+func TestRollbackForkedChild(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	parent := newTestProcess(k, code.Make(code.CALC, 3), code.Make(code.FORK), code.Make(code.CALC, 4), code.Make(code.WAIT, 1))
+	s.admit(parent)
+	parent.ip = 2
+
+	child := fork(t, s, parent)
+	start := child.ip
+
+	child.Execute(s)
+	child.rollback()
+
+	// Rolling back doesn't run the parent's FORK again
+	if child.ip != start || child.Runtime != 4 {
+		t.Errorf("child should restart past the FORK. want ip=%d, got ip=%d runtime=%d", start, child.ip, child.Runtime)
+	}
+}
@@ -0,0 +1,91 @@
+package sched
+
+import (
+	"sync"
+	"time"
+)
+
+// IODevice : simulated device that services IO requests one at a time
+//
+// A process doing IO waits in the device queue until its request has been
+// serviced for as many ticks as the IO instruction's operand, then the device
+// raises a completion interrupt so the kernel puts the process back in a ready queue.
+type IODevice struct {
+	ID    int           // Index of the device in the kernel
+	Speed time.Duration // Time it takes to service one tick of IO
+
+	queue  []*Process    // Processes waiting on the device, the front one is being serviced
+	notify chan struct{} // Wakes the device up when a request is queued
+	kernel *Kernel       // Kernel to interrupt when a request is done
+	mu     sync.Mutex    // Guards the queue
+}
+
+// InitIODevice : create new io device
+func InitIODevice(k *Kernel, id int, speed time.Duration) *IODevice {
+	return &IODevice{
+		ID:     id,
+		Speed:  speed,
+		queue:  []*Process{},
+		notify: make(chan struct{}, 1),
+		kernel: k,
+	}
+}
+
+// Len : number of processes waiting on the device
+func (d *IODevice) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.queue)
+}
+
+// park queues an IO request
+func (d *IODevice) park(p *Process) {
+	d.mu.Lock()
+	d.queue = append(d.queue, p)
+	d.mu.Unlock()
+
+	// Let the device know there's work without blocking if it already knows
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run : service requests until the kernel stops
+func (d *IODevice) Run() {
+	for {
+		select {
+		case <-d.notify:
+		case <-d.kernel.quit:
+			return
+		}
+
+		for {
+			d.mu.Lock()
+			if len(d.queue) == 0 {
+				d.mu.Unlock()
+				break
+			}
+
+			p := d.queue[0]
+			d.mu.Unlock()
+
+			// Do the IO
+			for ; p.ioTicks > 0; p.ioTicks-- {
+				time.Sleep(d.Speed)
+			}
+
+			d.mu.Lock()
+			d.queue = remove(d.queue, 0)
+			d.mu.Unlock()
+
+			// Completion interrupt
+			select {
+			case d.kernel.interrupts <- p:
+			case <-d.kernel.quit:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package sched
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
+
+// Kernel : resources shared between the schedulers of every CPU
+//
+// The kernel dispatches new processes to the least loaded scheduler and
+// periodically balances the ready queues so no CPU sits idle while another
+// has a backlog.
+type Kernel struct {
+	Schedulers        []*Scheduler   // One scheduler per CPU
+	Devices           []*IODevice    // IO devices shared by every CPU
+	Mem               *memory.Memory // Memory shared by every scheduler
+	InMsg             chan *Process  // Message channel where the kernel receives processes
+	MinimumFreeFrames int            // Minimum number of frames for a process to be made ready
+	Mailboxes         []*Mailbox     // Mailboxes for interprocess communication
+	Lock              *Lock          // Kernel lock for the critical section
+	BalanceInterval   time.Duration  // Time between load balancing passes
+	Detector          *Detector      // Deadlock detector, nil if deadlocks aren't looked for
+	Avoidance         bool           // Run the Banker's safety check before handing out the kernel lock or semaphore units
+	Children          *Children      // Parents waiting on their children
+	Cascade           bool           // Terminate the children of a process when it exits
+	PipeSize          int            // Values a pipe holds before WRITE blocks
+	Pager             *Pager         // Swaps in pages on a fault, nil if faults are serviced right away
+	PFF               *PFF           // Suspends processes when memory is overcommitted, nil to let it thrash
+	ProcessCache      *memory.Cache  // Slab cache process control blocks are allocated from, nil to leave them out of memory
+
+	init       *Process           // Parent of every process without one, never runs
+	procs      map[int]*Process   // Process table of every process admitted and not reaped
+	semaphores map[int]*Semaphore // Semaphores by the key programs use
+	conds      map[int]*Cond      // Condition variables by the key programs use
+	pipes      map[int]*Pipe      // Pipes with an end still open
+	nextPipe   int                // Number for the next pipe
+	interrupts chan *Process      // Processes whose IO is done
+	quit       chan struct{}      // Closed to stop the schedulers
+	mu         sync.Mutex         // Guards the process table and interprocess communication
+
+	events   []string   // Most recent things worth telling the user about
+	eventsMu sync.Mutex // Guards the events, separate so they can be logged while holding mu
+}
+
+const (
+	// MaxEvents : number of events the kernel remembers
+	MaxEvents = 100
+)
+
+// InitKernel : create new kernel without any CPUs
+func InitKernel(mem *memory.Memory, in chan *Process, minimumFreeFrames int, balanceInterval time.Duration) *Kernel {
+	k := &Kernel{
+		Schedulers:        []*Scheduler{},
+		Devices:           []*IODevice{},
+		Mem:               mem,
+		InMsg:             in,
+		MinimumFreeFrames: minimumFreeFrames,
+		Mailboxes:         []*Mailbox{},
+		BalanceInterval:   balanceInterval,
+		PipeSize:          DefaultPipeSize,
+		procs:             make(map[int]*Process),
+		semaphores:        make(map[int]*Semaphore),
+		conds:             make(map[int]*Cond),
+		pipes:             make(map[int]*Pipe),
+		interrupts:        make(chan *Process),
+		quit:              make(chan struct{}),
+		events:            []string{},
+	}
+
+	k.Lock = InitLock(k)
+	k.Children = InitChildren(k)
+	k.init = &Process{PID: 0, Name: "init", children: []*Process{}, pcb: -1}
+
+	return k
+}
+
+// AddCPU : give the kernel another CPU with its own scheduler
+func (k *Kernel) AddCPU(cpu *cpu.CPU, policy SchedulingPolicy) *Scheduler {
+	s := InitScheduler(k, len(k.Schedulers), cpu, policy)
+
+	k.Schedulers = append(k.Schedulers, s)
+
+	return s
+}
+
+// AddDevice : give the kernel another IO device
+func (k *Kernel) AddDevice(speed time.Duration) *IODevice {
+	d := InitIODevice(k, len(k.Devices), speed)
+
+	k.Devices = append(k.Devices, d)
+
+	return d
+}
+
+// AddMailbox : give the kernel another mailbox that holds up to size messages
+func (k *Kernel) AddMailbox(size int) *Mailbox {
+	mb := InitMailbox(k, len(k.Mailboxes), size)
+
+	k.Mailboxes = append(k.Mailboxes, mb)
+
+	return mb
+}
+
+// AddPager : have page faults block the faulting process for latency while the page is swapped in
+func (k *Kernel) AddPager(latency time.Duration) *Pager {
+	k.Pager = InitPager(k, latency)
+
+	return k.Pager
+}
+
+// AddPFF : have the kernel check for thrashing every interval, suspending processes above
+// the upper fault rate and resuming them below the lower one
+func (k *Kernel) AddPFF(interval time.Duration, lower float64, upper float64) *PFF {
+	k.PFF = InitPFF(k, interval, lower, upper)
+
+	return k.PFF
+}
+
+// AddDetector : have the kernel look for deadlocks every interval and recover from them
+func (k *Kernel) AddDetector(interval time.Duration, recovery string) *Detector {
+	k.Detector = InitDetector(k, interval, recovery)
+
+	return k.Detector
+}
+
+// Run : start every scheduler, device, the pager, the thrashing controller and the load balancer, then dispatch processes until the process channel closes
+func (k *Kernel) Run() {
+
+	for _, s := range k.Schedulers {
+		go s.Run()
+	}
+
+	for _, d := range k.Devices {
+		go d.Run()
+	}
+
+	if k.Pager != nil {
+		go k.Pager.Run()
+	}
+
+	go k.balance()
+
+	if k.Detector != nil {
+		go k.Detector.Run()
+	}
+
+	if k.PFF != nil {
+		go k.PFF.Run()
+	}
+
+	k.recvProc()
+}
+
+// Stop : stop the schedulers and load balancer
+func (k *Kernel) Stop() {
+	close(k.quit)
+}
+
+// recvProc keeps an eye on the process channel and device interrupts and dispatches processes
+func (k *Kernel) recvProc() {
+
+	for {
+
+		// Checks for new processes to schedule
+		select {
+		case x, ok := <-k.InMsg:
+			if !ok {
+				// Channel is closed to execution must exit
+				return
+			}
+
+			k.register(x)
+			k.leastLoaded().admit(x)
+
+		case x := <-k.interrupts:
+			k.wake(x)
+
+		case <-k.quit:
+			return
+		}
+	}
+}
+
+// Live : number of processes in the process table
+func (k *Kernel) Live() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.procs)
+}
+
+// Segments : segment table of every process in the process table by PID
+func (k *Kernel) Segments() map[int][]memory.Segment {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	tables := make(map[int][]memory.Segment, len(k.procs))
+	for pid, p := range k.procs {
+		if !p.zombie {
+			tables[pid] = k.Mem.SegmentTable(pid)
+		}
+	}
+
+	return tables
+}
+
+// MailboxDepths : number of messages in each mailbox
+func (k *Kernel) MailboxDepths() []int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	depths := make([]int, len(k.Mailboxes))
+	for i, mb := range k.Mailboxes {
+		depths[i] = mb.Len()
+	}
+
+	return depths
+}
+
+// SemaphoreCounts : units left in each semaphore by key
+func (k *Kernel) SemaphoreCounts() map[int]int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	counts := make(map[int]int, len(k.semaphores))
+	for id, sem := range k.semaphores {
+		counts[id] = sem.Count()
+	}
+
+	return counts
+}
+
+// semaphore gets the semaphore for a key, nil if no process has initialized it with SEMINIT, the kernel lock must be held
+func (k *Kernel) semaphore(id int) *Semaphore {
+	return k.semaphores[id]
+}
+
+// cond gets the condition variable for a key, the kernel lock must be held
+func (k *Kernel) cond(id int) *Cond {
+	c, ok := k.conds[id]
+	if !ok {
+		c = InitCond(k, id)
+		k.conds[id] = c
+	}
+
+	return c
+}
+
+// register adds a new process to the process table and gives it a mailbox and its process control block,
+// the process still runs without a control block and the cache counts the failure
+func (k *Kernel) register(p *Process) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.procs[p.PID] = p
+
+	// Waiting for memory or not, every process in the table costs the kernel memory
+	if p.pcb < 0 && k.ProcessCache != nil {
+		p.pcb, _ = k.ProcessCache.Alloc()
+	}
+
+	// Processes without a living parent belong to init
+	if p.parent == nil || p.parent.zombie || k.procs[p.parent.PID] != p.parent {
+		p.parent = k.init
+	}
+
+	if len(k.Mailboxes) > 0 {
+		p.assignedMailbox = p.PID % len(k.Mailboxes)
+	}
+}
+
+// unregister takes a finished process out of the process tree, it stays in the process table and keeps its
+// process control block until it's reaped, right away unless a parent can still wait on it
+func (k *Kernel) unregister(p *Process) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.orphan(p)
+	k.bury(p)
+
+	// Its claims are gone so someone kept waiting might be safe to go now
+	k.grant()
+}
+
+// grant hands out the kernel lock and semaphore units nobody holds to processes waiting on them, the kernel lock must be held
+func (k *Kernel) grant() {
+	k.Lock.grant()
+
+	for _, sem := range k.semaphores {
+		sem.grant()
+	}
+}
+
+// releaseAll takes back the resources of a process that is done
+func (k *Kernel) releaseAll(p *Process) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if owner, locked := k.Lock.Owner(); locked && owner == p.PID {
+		k.logEvent("process %d exited while holding the kernel lock", p.PID)
+		k.Lock.release(p)
+	}
+
+	// Descriptors close when the process exits
+	for fd, d := range p.fds {
+		if d != nil {
+			k.closeFd(p, fd)
+		}
+	}
+
+	// Units it took are gone with it, the semaphore counts stay as they are
+	for _, sem := range k.semaphores {
+		delete(sem.holders, p.PID)
+	}
+}
+
+// CheckMemory : check every page outside of physical memory against its checksum and log what changed,
+// returns the IDs of the pages that did
+func (k *Kernel) CheckMemory() []int {
+	bad := k.Mem.Check()
+	if len(bad) == 0 {
+		k.logEvent("memory check: every page is intact")
+		return bad
+	}
+
+	for _, id := range bad {
+		k.logEvent("memory check: page %d changed while it was out of memory", id)
+	}
+
+	return bad
+}
+
+// Events : most recent events, oldest first
+func (k *Kernel) Events() []string {
+	k.eventsMu.Lock()
+	defer k.eventsMu.Unlock()
+
+	return append([]string{}, k.events...)
+}
+
+// logEvent remembers something worth telling the user about
+func (k *Kernel) logEvent(format string, args ...interface{}) {
+	k.eventsMu.Lock()
+	defer k.eventsMu.Unlock()
+
+	k.events = append(k.events, fmt.Sprintf(format, args...))
+
+	if len(k.events) > MaxEvents {
+		k.events = k.events[len(k.events)-MaxEvents:]
+	}
+}
+
+// wake puts a process that was blocked back in a ready queue
+func (k *Kernel) wake(p *Process) {
+	p.waitingOn = nil
+
+	k.leastLoaded().ready(p)
+}
+
+// leastLoaded : scheduler with the fewest processes to run
+func (k *Kernel) leastLoaded() *Scheduler {
+	least := k.Schedulers[0]
+	leastLoad := least.Load()
+
+	for _, s := range k.Schedulers[1:] {
+		if load := s.Load(); load < leastLoad {
+			least, leastLoad = s, load
+		}
+	}
+
+	return least
+}
+
+// balance periodically pushes processes from the busiest scheduler to the idlest
+func (k *Kernel) balance() {
+
+	ticker := time.NewTicker(k.BalanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			k.push()
+		case <-k.quit:
+			return
+		}
+	}
+}
+
+// push migration: even out the busiest and idlest ready queues
+func (k *Kernel) push() {
+	if len(k.Schedulers) < 2 {
+		return
+	}
+
+	busiest, idlest := k.Schedulers[0], k.Schedulers[0]
+	for _, s := range k.Schedulers {
+		if s.Load() > busiest.Load() {
+			busiest = s
+		}
+
+		if s.Load() < idlest.Load() {
+			idlest = s
+		}
+	}
+
+	if busiest == idlest {
+		return
+	}
+
+	lockPair(busiest, idlest)
+	defer unlockPair(busiest, idlest)
+
+	// Move half of the difference so both end up about even
+	n := (busiest.load() - idlest.load()) / 2
+	migrate(busiest, idlest, n)
+}
+
+// pull migration: an idle scheduler takes a process from the busiest ready queue
+func (k *Kernel) pull(idle *Scheduler) bool {
+
+	var busiest *Scheduler
+	for _, s := range k.Schedulers {
+		if s != idle && (busiest == nil || s.Load() > busiest.Load()) {
+			busiest = s
+		}
+	}
+
+	if busiest == nil {
+		return false
+	}
+
+	lockPair(busiest, idle)
+	defer unlockPair(busiest, idle)
+
+	return migrate(busiest, idle, 1) > 0
+}
+
+// migrate moves up to n processes from the back of one ready queue to another, both schedulers must be locked
+func migrate(from *Scheduler, to *Scheduler, n int) int {
+
+	moved := 0
+	for ; moved < n && len(from.ReadyQ) > 0; moved++ {
+		last := len(from.ReadyQ) - 1
+
+		p := from.ReadyQ[last]
+		from.ReadyQ = remove(from.ReadyQ, last)
+
+		to.ReadyQ = append(to.ReadyQ, p)
+	}
+
+	return moved
+}
+
+// lockPair locks two schedulers in a consistent order so balancing can't deadlock
+func lockPair(a *Scheduler, b *Scheduler) {
+	if a.ID > b.ID {
+		a, b = b, a
+	}
+
+	a.mu.Lock()
+	b.mu.Lock()
+}
+
+// unlockPair unlocks two schedulers locked with lockPair
+func unlockPair(a *Scheduler, b *Scheduler) {
+	a.mu.Unlock()
+	b.mu.Unlock()
+}
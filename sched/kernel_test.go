@@ -0,0 +1,222 @@
+package sched
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+)
+
+// idle checks if every process sent to the kernel has finished
+func idle(k *Kernel) bool {
+	return len(k.InMsg) == 0 && k.Live() == 0
+}
+
+// waitForIdle fails the test if the kernel doesn't finish its processes in time
+func waitForIdle(t *testing.T, k *Kernel, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+
+	// Check twice in case a process was between the channel and a scheduler
+	for settled := 0; settled < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("kernel didn't finish its processes in %s", timeout)
+		}
+
+		if idle(k) {
+			settled++
+		} else {
+			settled = 0
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatchToLeastLoaded(t *testing.T) {
+	k := newTestKernel()
+	s1 := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	s2 := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	for i := 0; i < 4; i++ {
+		k.leastLoaded().admit(CreateProcess("dispatch", 1, 32, code.Make(code.CALC, 1), 0, nil))
+	}
+
+	if len(s1.ReadyQ) != 2 || len(s2.ReadyQ) != 2 {
+		t.Errorf("processes should be split evenly. got=%d and %d", len(s1.ReadyQ), len(s2.ReadyQ))
+	}
+}
+
+func TestMigration(t *testing.T) {
+	k := newTestKernel()
+	s1 := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	s2 := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	for i := 0; i < 6; i++ {
+		s1.admit(CreateProcess("migrate", 1, 32, code.Make(code.CALC, 1), 0, nil))
+	}
+
+	// Push evens out the queues
+	k.push()
+
+	if len(s1.ReadyQ) != 3 || len(s2.ReadyQ) != 3 {
+		t.Errorf("push should even out the queues. got=%d and %d", len(s1.ReadyQ), len(s2.ReadyQ))
+	}
+
+	// Pull takes one process for an idle CPU
+	s2.ReadyQ = s2.ReadyQ[:0]
+	if !k.pull(s2) {
+		t.Fatalf("idle scheduler should pull a process")
+	}
+
+	if len(s1.ReadyQ) != 2 || len(s2.ReadyQ) != 1 {
+		t.Errorf("pull should move one process. got=%d and %d", len(s1.ReadyQ), len(s2.ReadyQ))
+	}
+}
+
+func TestKernelRunsProcessesOnEveryCPU(t *testing.T) {
+	k := newTestKernel()
+	s1 := k.AddCPU(cpu.InitCPU(10*time.Microsecond), NewRoundRobin(5))
+	s2 := k.AddCPU(cpu.InitCPU(10*time.Microsecond), NewRoundRobin(5))
+
+	go k.Run()
+	defer k.Stop()
+
+	for i := 0; i < 50; i++ {
+		k.InMsg <- CreateProcess("kernel", 20, 32, code.Make(code.CALC, 20), 0, nil)
+	}
+
+	waitForIdle(t, k, 10*time.Second)
+
+	if s1.CPU.TotalCycles == 0 || s2.CPU.TotalCycles == 0 {
+		t.Errorf("both CPUs should have run processes. got=%d and %d", s1.CPU.TotalCycles, s2.CPU.TotalCycles)
+	}
+
+	if total := s1.CPU.TotalCycles + s2.CPU.TotalCycles; total != 50*20 {
+		t.Errorf("wrong number of cycles. want=%d, got=%d", 50*20, total)
+	}
+}
+
+func TestIOBlocksProcess(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(50))
+	d := k.AddDevice(time.Millisecond)
+
+	program := append(code.Make(code.CALC, 2), code.Make(code.IO, 5)...)
+	program = append(program, code.Make(code.CALC, 3)...)
+	p := CreateProcess("io", 5, 32, program, 0, nil)
+
+	k.register(p)
+	s.runProcess(p)
+
+	if p.State != WAIT {
+		t.Fatalf("process should be waiting on IO. want=%d, got=%d", WAIT, p.State)
+	}
+
+	if d.Len() != 1 {
+		t.Fatalf("process should be in the device queue. got=%d", d.Len())
+	}
+
+	if p.Runtime != 3 {
+		t.Errorf("process should only have run until the IO. want=3, got=%d", p.Runtime)
+	}
+
+	// Let the device service the request and the kernel wake the process back up
+	go k.Run()
+	defer k.Stop()
+
+	waitForIdle(t, k, 10*time.Second)
+
+	if s.CPU.TotalCycles != 5 {
+		t.Errorf("wrong number of cycles. want=5, got=%d", s.CPU.TotalCycles)
+	}
+}
+
+func TestStressWithPollingReaders(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	k := newTestKernel()
+	for i := 0; i < 4; i++ {
+		k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	}
+	k.AddDevice(0)
+	k.AddDevice(0)
+
+	go k.Run()
+	defer k.Stop()
+
+	// Poll everything the TUI shows while the processes run
+	done := make(chan struct{})
+	polled := make(chan int)
+	go func() {
+		polls := 0
+		for {
+			select {
+			case <-done:
+				polled <- polls
+				return
+			default:
+			}
+
+			for _, s := range k.Schedulers {
+				s.ReadySnapshot()
+				s.WaitingSnapshot()
+			}
+			k.Mem.Usage()
+
+			polls++
+		}
+	}()
+
+	// Several loaders share the same template like the `load` command does
+	template := [][]string{
+		{"CALC", "10"},
+		{"IO", "5"},
+		{"CALC", "8"},
+	}
+
+	var loaders sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		loaders.Add(1)
+		go func() {
+			defer loaders.Done()
+
+			for j := 0; j < 1000; j++ {
+				CreateRandomProcessFromTemplate("stress", 32, nil, nil, template, k.InMsg)
+			}
+		}()
+	}
+
+	loaders.Wait()
+	waitForIdle(t, k, 60*time.Second)
+
+	if template[0][1] != "10" {
+		t.Errorf("jittering processes shouldn't change the template. got=%s", template[0][1])
+	}
+
+	close(done)
+	if polls := <-polled; polls == 0 {
+		t.Errorf("readers never polled the kernel")
+	}
+
+	if physical, virtual := k.Mem.Usage(); physical != 0 || virtual != 0 {
+		t.Errorf("finished processes should free their memory. got physical=%d, virtual=%d", physical, virtual)
+	}
+}
+
+func TestCheckMemoryLogsResult(t *testing.T) {
+	k := newTestKernel()
+
+	k.Mem.Add(64, 1)
+
+	if bad := k.CheckMemory(); len(bad) != 0 {
+		t.Errorf("fresh pages should be intact. got=%v", bad)
+	}
+
+	if events := k.Events(); len(events) != 1 || events[0] != "memory check: every page is intact" {
+		t.Errorf("check should be logged. got=%v", events)
+	}
+}
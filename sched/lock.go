@@ -0,0 +1,116 @@
+package sched
+
+import (
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
+
+// Lock : kernel lock guarding the critical section
+//
+// ENTER acquires the lock, if another process holds it the process waits in
+// line off the CPU. EXIT hands the lock straight to the next process in line
+// and wakes it already inside the critical section. Everything is guarded by
+// the kernel lock.
+type Lock struct {
+	mutex   memory.Mutex // Who holds the lock
+	waiters []*Process   // Processes waiting for the lock, first come first serve
+	kernel  *Kernel
+}
+
+// InitLock : create new kernel lock
+func InitLock(k *Kernel) *Lock {
+	return &Lock{
+		waiters: []*Process{},
+		kernel:  k,
+	}
+}
+
+// acquire tries to take the lock for the process and enter the critical section
+func (l *Lock) acquire(p *Process) bool {
+	if _, locked := l.mutex.Owner(); locked {
+		return false
+	}
+
+	// Taking it could lead to a deadlock
+	if !l.kernel.safe(p, LockKey) {
+		return false
+	}
+
+	l.mutex.Acquire(p.PID)
+	p.Critical = true
+	return true
+}
+
+// release gives up the lock and hands it to the next process in line that can have it
+func (l *Lock) release(p *Process) error {
+	if err := l.mutex.Release(p.PID); err != nil {
+		return err
+	}
+
+	p.Critical = false
+
+	// Anything given back can make a request kept waiting by avoidance safe
+	if l.kernel.Avoidance {
+		l.kernel.grant()
+		return nil
+	}
+
+	l.grant()
+	return nil
+}
+
+// grant gives a free lock to the first process in line that can have it and wakes it past its ENTER (or CWAIT)
+func (l *Lock) grant() {
+	for i, next := range l.waiters {
+		if !l.acquire(next) {
+			continue
+		}
+
+		l.waiters = remove(l.waiters, i)
+		next.ip += code.Width(code.Opcode(next.ins[next.ip]))
+
+		l.kernel.wake(next)
+		return
+	}
+}
+
+// park waits in line for the lock
+func (l *Lock) park(p *Process) {
+	l.kernel.mu.Lock()
+	defer l.kernel.mu.Unlock()
+
+	// Lock was released in the meantime
+	if l.acquire(p) {
+		p.ip++
+		l.kernel.wake(p)
+		return
+	}
+
+	l.waiters = append(l.waiters, p)
+}
+
+// Owner : process holding the lock, false if nobody does
+func (l *Lock) Owner() (int, bool) {
+	return l.mutex.Owner()
+}
+
+// String : string representation of lock
+func (l *Lock) String() string {
+	return "kernel lock"
+}
+
+// waiting : processes in line for the lock
+func (l *Lock) waiting() []*Process {
+	return l.waiters
+}
+
+// wakes : only the owner can release the lock
+func (l *Lock) wakes(p *Process, q *Process, from int) bool {
+	owner, locked := l.mutex.Owner()
+	return locked && owner == q.PID
+}
+
+// cancel takes a process out of line for the lock
+func (l *Lock) cancel(p *Process) {
+	l.waiters = removeProcess(l.waiters, p)
+}
@@ -0,0 +1,146 @@
+package sched
+
+import (
+	"fmt"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+)
+
+// Mailbox : bounded buffer of messages for interprocess communication
+//
+// RECV blocks while the mailbox is empty and SEND blocks while it's full.
+// Blocked processes stay on the SEND or RECV they blocked on, when the
+// other side shows up the message is handed over directly and the blocked
+// process is woken past its instruction. Everything is guarded by the kernel lock.
+type Mailbox struct {
+	ID   int // Index of the mailbox in the kernel
+	Size int // Maximum number of messages held
+
+	messages  []byte     // Messages waiting to be received, oldest first
+	senders   []*Process // Processes blocked on SEND
+	receivers []*Process // Processes blocked on RECV
+	kernel    *Kernel
+}
+
+// InitMailbox : create new mailbox
+func InitMailbox(k *Kernel, id int, size int) *Mailbox {
+	return &Mailbox{
+		ID:        id,
+		Size:      size,
+		messages:  make([]byte, 0, size),
+		senders:   []*Process{},
+		receivers: []*Process{},
+		kernel:    k,
+	}
+}
+
+// send tries to deliver a message, false means the mailbox is full
+func (mb *Mailbox) send(value byte) bool {
+
+	// Someone is already waiting for it
+	if len(mb.receivers) > 0 {
+		r := mb.receivers[0]
+		mb.receivers = remove(mb.receivers, 0)
+
+		r.acc = value
+		r.ip++
+
+		mb.kernel.wake(r)
+		return true
+	}
+
+	if len(mb.messages) >= mb.Size {
+		return false
+	}
+
+	mb.messages = append(mb.messages, value)
+	return true
+}
+
+// recv tries to take a message, false means the mailbox is empty
+func (mb *Mailbox) recv() (byte, bool) {
+	if len(mb.messages) == 0 {
+		return 0, false
+	}
+
+	value := mb.messages[0]
+	mb.messages = mb.messages[1:]
+
+	// Make room for someone blocked on a full mailbox
+	if len(mb.senders) > 0 {
+		s := mb.senders[0]
+		mb.senders = remove(mb.senders, 0)
+
+		mb.messages = append(mb.messages, s.ins[s.ip+1])
+		s.ip += 2
+
+		mb.kernel.wake(s)
+	}
+
+	return value, true
+}
+
+// park waits for the other side of the SEND or RECV the process is on
+func (mb *Mailbox) park(p *Process) {
+	mb.kernel.mu.Lock()
+	defer mb.kernel.mu.Unlock()
+
+	switch code.Opcode(p.ins[p.ip]) {
+	case code.SEND:
+
+		// Room opened up in the meantime
+		if mb.send(p.ins[p.ip+1]) {
+			p.ip += 2
+			mb.kernel.wake(p)
+			return
+		}
+
+		mb.senders = append(mb.senders, p)
+
+	case code.RECV:
+
+		// A message came in the meantime
+		if value, ok := mb.recv(); ok {
+			p.acc = value
+			p.ip++
+			mb.kernel.wake(p)
+			return
+		}
+
+		mb.receivers = append(mb.receivers, p)
+	}
+}
+
+// Len : number of messages in the mailbox, the kernel lock must be held
+func (mb *Mailbox) Len() int {
+	return len(mb.messages)
+}
+
+// String : string representation of mailbox
+func (mb *Mailbox) String() string {
+	return fmt.Sprintf("mailbox %d", mb.ID)
+}
+
+// waiting : processes blocked on SEND or RECV
+func (mb *Mailbox) waiting() []*Process {
+	return append(append([]*Process{}, mb.senders...), mb.receivers...)
+}
+
+// wakes : anyone on the same mailbox with the other side of the SEND or RECV left
+func (mb *Mailbox) wakes(p *Process, q *Process, from int) bool {
+	if q.assignedMailbox != mb.ID {
+		return false
+	}
+
+	if code.Opcode(p.ins[p.ip]) == code.SEND {
+		return q.mayDo(code.RECV, -1, from)
+	}
+
+	return q.mayDo(code.SEND, -1, from)
+}
+
+// cancel takes a process out of line on the mailbox
+func (mb *Mailbox) cancel(p *Process) {
+	mb.senders = removeProcess(mb.senders, p)
+	mb.receivers = removeProcess(mb.receivers, p)
+}
@@ -0,0 +1,111 @@
+package sched
+
+import "fmt"
+
+// MultilevelFeedbackQueue : ready processes are split into levels by their priority,
+// the highest level always runs first and processes move between levels based on how
+// they use the CPU
+//
+// Level 0 is the highest priority. The ready queue is treated as one FIFO queue per
+// level, so processes in the same level are run round robin.
+type MultilevelFeedbackQueue struct {
+	Quanta        []int // Time quantum for each level, the number of levels is len(Quanta)
+	BoostInterval int   // Cycles between moving every process back to the top level
+
+	lastBoost int
+}
+
+// NewMultilevelFeedbackQueue : create mlfq policy
+func NewMultilevelFeedbackQueue(levels int, quanta []int, boostInterval int) (*MultilevelFeedbackQueue, error) {
+
+	if levels <= 0 {
+		return nil, fmt.Errorf("mlfq needs at least one level")
+	}
+
+	if len(quanta) != levels {
+		return nil, fmt.Errorf("mlfq needs a quantum for each level. want=%d, got=%d", levels, len(quanta))
+	}
+
+	for _, q := range quanta {
+		if q <= 0 {
+			return nil, fmt.Errorf("mlfq quanta must be above zero")
+		}
+	}
+
+	if boostInterval <= 0 {
+		return nil, fmt.Errorf("mlfq boost interval must be above zero")
+	}
+
+	return &MultilevelFeedbackQueue{
+		Quanta:        quanta,
+		BoostInterval: boostInterval,
+	}, nil
+}
+
+// Next : first process in the highest non-empty level
+func (m *MultilevelFeedbackQueue) Next(readyQ []*Process, now int) int {
+
+	// Periodically move everyone to the top so low levels don't starve
+	if now-m.lastBoost >= m.BoostInterval {
+		m.lastBoost = now
+
+		for _, p := range readyQ {
+			p.priority = 0
+		}
+	}
+
+	next := 0
+	for i, p := range readyQ {
+		if m.level(p) < m.level(readyQ[next]) {
+			next = i
+		}
+	}
+
+	return next
+}
+
+// Quantum : lower levels get longer quanta
+func (m *MultilevelFeedbackQueue) Quantum(p *Process) int {
+	return m.Quanta[m.level(p)]
+}
+
+// Preempt : a process in a higher level became ready
+func (m *MultilevelFeedbackQueue) Preempt(cur *Process, readyQ []*Process) bool {
+	for _, p := range readyQ {
+		if m.level(p) < m.level(cur) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OnQuantumExpire : burning a full quantum means the process is CPU bound so demote it
+func (m *MultilevelFeedbackQueue) OnQuantumExpire(p *Process) {
+	if p.priority < len(m.Quanta)-1 {
+		p.priority++
+	}
+}
+
+// OnBlock : giving up the CPU early (e.g. for IO) means the process is interactive so promote it
+func (m *MultilevelFeedbackQueue) OnBlock(p *Process) {
+	if p.priority > 0 {
+		p.priority--
+	}
+}
+
+// OnExit : nothing to do
+func (m *MultilevelFeedbackQueue) OnExit(p *Process) {}
+
+// level clamps the priority of a process to a valid level
+func (m *MultilevelFeedbackQueue) level(p *Process) int {
+	if p.priority < 0 {
+		return 0
+	}
+
+	if p.priority >= len(m.Quanta) {
+		return len(m.Quanta) - 1
+	}
+
+	return p.priority
+}
@@ -0,0 +1,89 @@
+package sched
+
+import (
+	"sync"
+	"time"
+)
+
+// Pager : simulated backing store that services page faults one at a time
+//
+// A process that touches a page outside of physical memory waits in the
+// pager queue while the page is swapped in, then the pager raises an
+// interrupt so the kernel puts the process back in a ready queue to retry
+// the instruction that faulted.
+type Pager struct {
+	Latency time.Duration // Time it takes to swap in one page
+
+	queue  []*Process    // Processes waiting on a page, the front one is being serviced
+	notify chan struct{} // Wakes the pager up when a fault is queued
+	kernel *Kernel       // Kernel to interrupt when a page is in
+	mu     sync.Mutex    // Guards the queue
+}
+
+// InitPager : create new pager
+func InitPager(k *Kernel, latency time.Duration) *Pager {
+	return &Pager{
+		Latency: latency,
+		queue:   []*Process{},
+		notify:  make(chan struct{}, 1),
+		kernel:  k,
+	}
+}
+
+// Len : number of processes waiting on a page
+func (pg *Pager) Len() int {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	return len(pg.queue)
+}
+
+// park queues a page fault
+func (pg *Pager) park(p *Process) {
+	pg.mu.Lock()
+	pg.queue = append(pg.queue, p)
+	pg.mu.Unlock()
+
+	// Let the pager know there's work without blocking if it already knows
+	select {
+	case pg.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run : service page faults until the kernel stops
+func (pg *Pager) Run() {
+	for {
+		select {
+		case <-pg.notify:
+		case <-pg.kernel.quit:
+			return
+		}
+
+		for {
+			pg.mu.Lock()
+			if len(pg.queue) == 0 {
+				pg.mu.Unlock()
+				break
+			}
+
+			p := pg.queue[0]
+			pg.mu.Unlock()
+
+			// Swap the page in
+			time.Sleep(pg.Latency)
+			pg.kernel.Mem.PageIn(p.PID, p.faultPage)
+
+			pg.mu.Lock()
+			pg.queue = remove(pg.queue, 0)
+			pg.mu.Unlock()
+
+			// Page is in
+			select {
+			case pg.kernel.interrupts <- p:
+			case <-pg.kernel.quit:
+				return
+			}
+		}
+	}
+}
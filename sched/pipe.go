@@ -0,0 +1,268 @@
+package sched
+
+import (
+	"fmt"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+)
+
+const (
+	// DefaultPipeSize : values a pipe holds before WRITE blocks unless the kernel is told otherwise
+	DefaultPipeSize = 16
+)
+
+// Pipe : bounded buffer between the processes holding its descriptors
+//
+// READ blocks while the pipe is empty and someone can still write to it, once
+// every write end is closed it reads end of file. WRITE blocks while the pipe
+// is full and terminates the process once every read end is closed. Blocked
+// processes stay on the READ or WRITE they blocked on and are woken past it.
+// Everything is guarded by the kernel lock.
+type Pipe struct {
+	ID   int // Pipe number in the kernel
+	Size int // Maximum number of values held
+
+	buffer       []byte     // Values written and not read yet, oldest first
+	readers      int        // Open read ends
+	writers      int        // Open write ends
+	readWaiters  []*Process // Processes blocked on READ
+	writeWaiters []*Process // Processes blocked on WRITE
+	kernel       *Kernel
+}
+
+// descriptor : one end of a pipe open in a process
+type descriptor struct {
+	pipe  *Pipe
+	write bool // Write end if true, read end otherwise
+}
+
+// InitPipe : create new pipe with no ends open
+func InitPipe(k *Kernel, id int, size int) *Pipe {
+	return &Pipe{
+		ID:           id,
+		Size:         size,
+		buffer:       make([]byte, 0, size),
+		readWaiters:  []*Process{},
+		writeWaiters: []*Process{},
+		kernel:       k,
+	}
+}
+
+// read tries to read a value into the accumulator, false means the process has to wait for a writer
+func (pp *Pipe) read(p *Process) bool {
+	if len(pp.buffer) == 0 {
+		if pp.writers > 0 {
+			return false
+		}
+
+		// End of file
+		p.acc = 0
+		return true
+	}
+
+	p.acc = pp.buffer[0]
+	pp.buffer = pp.buffer[1:]
+
+	// Make room for someone blocked on a full pipe
+	if len(pp.writeWaiters) > 0 {
+		w := pp.writeWaiters[0]
+		pp.writeWaiters = remove(pp.writeWaiters, 0)
+
+		pp.buffer = append(pp.buffer, w.ins[w.ip+2])
+		w.ip += 3
+
+		pp.kernel.wake(w)
+	}
+
+	return true
+}
+
+// write tries to write a value, false means the pipe is full
+func (pp *Pipe) write(value byte) bool {
+
+	// Someone is already waiting for it
+	if len(pp.readWaiters) > 0 {
+		r := pp.readWaiters[0]
+		pp.readWaiters = remove(pp.readWaiters, 0)
+
+		r.acc = value
+		r.ip += 2
+
+		pp.kernel.wake(r)
+		return true
+	}
+
+	if len(pp.buffer) >= pp.Size {
+		return false
+	}
+
+	pp.buffer = append(pp.buffer, value)
+	return true
+}
+
+// open adds a descriptor for one end of the pipe
+func (pp *Pipe) open(write bool) *descriptor {
+	if write {
+		pp.writers++
+	} else {
+		pp.readers++
+	}
+
+	return &descriptor{pipe: pp, write: write}
+}
+
+// close gives up a descriptor for one end of the pipe
+func (pp *Pipe) close(d *descriptor) {
+	k := pp.kernel
+
+	if d.write {
+		pp.writers--
+
+		// Nobody is going to write anymore so readers get end of file
+		if pp.writers == 0 {
+			for _, r := range pp.readWaiters {
+				r.acc = 0
+				r.ip += 2
+				k.wake(r)
+			}
+
+			pp.readWaiters = pp.readWaiters[:0]
+		}
+	} else {
+		pp.readers--
+
+		// Nobody is going to read what they write, trying the WRITE again fails it with a broken pipe
+		if pp.readers == 0 {
+			for _, w := range pp.writeWaiters {
+				k.wake(w)
+			}
+
+			pp.writeWaiters = pp.writeWaiters[:0]
+		}
+	}
+
+	if pp.readers == 0 && pp.writers == 0 {
+		delete(k.pipes, pp.ID)
+	}
+}
+
+// park waits for the other end of the READ or WRITE the process is on
+func (pp *Pipe) park(p *Process) {
+	pp.kernel.mu.Lock()
+	defer pp.kernel.mu.Unlock()
+
+	switch code.Opcode(p.ins[p.ip]) {
+	case code.WRITE:
+
+		// The readers left in the meantime, trying the WRITE again fails it with a broken pipe
+		if pp.readers == 0 {
+			pp.kernel.wake(p)
+			return
+		}
+
+		// Room opened up in the meantime
+		if pp.write(p.ins[p.ip+2]) {
+			p.ip += 3
+			pp.kernel.wake(p)
+			return
+		}
+
+		pp.writeWaiters = append(pp.writeWaiters, p)
+
+	case code.READ:
+
+		// Something was written in the meantime
+		if pp.read(p) {
+			p.ip += 2
+			pp.kernel.wake(p)
+			return
+		}
+
+		pp.readWaiters = append(pp.readWaiters, p)
+	}
+}
+
+// String : string representation of pipe
+func (pp *Pipe) String() string {
+	return fmt.Sprintf("pipe %d", pp.ID)
+}
+
+// waiting : processes blocked on READ or WRITE
+func (pp *Pipe) waiting() []*Process {
+	return append(append([]*Process{}, pp.readWaiters...), pp.writeWaiters...)
+}
+
+// wakes : anyone holding the other end of the pipe
+func (pp *Pipe) wakes(p *Process, q *Process, from int) bool {
+	writing := code.Opcode(p.ins[p.ip]) == code.WRITE
+
+	for _, d := range q.fds {
+		if d != nil && d.pipe == pp && d.write != writing {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cancel takes a process out of line on the pipe
+func (pp *Pipe) cancel(p *Process) {
+	pp.readWaiters = removeProcess(pp.readWaiters, p)
+	pp.writeWaiters = removeProcess(pp.writeWaiters, p)
+}
+
+// pipe makes a new pipe with both ends open in the process, the kernel lock must be held
+func (k *Kernel) pipe(p *Process) {
+	pp := InitPipe(k, k.nextPipe, k.PipeSize)
+	k.nextPipe++
+	k.pipes[pp.ID] = pp
+
+	p.openFd(pp.open(false))
+	p.openFd(pp.open(true))
+}
+
+// closeFd closes a descriptor of the process, the kernel lock must be held
+func (k *Kernel) closeFd(p *Process, fd int) error {
+	d := p.fd(fd)
+	if d == nil {
+		return fmt.Errorf("bad file descriptor %d", fd)
+	}
+
+	p.fds[fd] = nil
+	d.pipe.close(d)
+
+	return nil
+}
+
+// fd : open descriptor, nil if it isn't open
+func (p *Process) fd(fd int) *descriptor {
+	if fd < 0 || fd >= len(p.fds) {
+		return nil
+	}
+
+	return p.fds[fd]
+}
+
+// openFd puts a descriptor in the lowest free slot of the descriptor table
+func (p *Process) openFd(d *descriptor) int {
+	for fd, open := range p.fds {
+		if open == nil {
+			p.fds[fd] = d
+			return fd
+		}
+	}
+
+	p.fds = append(p.fds, d)
+	return len(p.fds) - 1
+}
+
+// inheritFds gives a child a copy of the descriptor table, the kernel lock must be held
+func (p *Process) inheritFds(child *Process) {
+	child.fds = make([]*descriptor, len(p.fds))
+
+	for fd, d := range p.fds {
+		if d != nil {
+			child.fds[fd] = d.pipe.open(d.write)
+		}
+	}
+}
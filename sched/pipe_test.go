@@ -0,0 +1,136 @@
+package sched
+
+import (
+	"testing"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+)
+
+// fork runs the FORK the process is on and registers the child it sends to the kernel
+func fork(t *testing.T, s *Scheduler, p *Process) *Process {
+	if err := p.Execute(s); err != nil {
+		t.Fatalf("FORK shouldn't fail. got=%v", err)
+	}
+
+	child := <-s.kernel.InMsg
+	s.kernel.register(child)
+
+	return child
+}
+
+func TestPipeSharedAcrossFork(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	k.PipeSize = 1
+
+	parent := newTestProcess(k,
+		code.Make(code.PIPE), code.Make(code.FORK),
+		code.Make(code.WRITE, 1, 5), code.Make(code.WRITE, 1, 6), code.Make(code.WRITE, 1, 7),
+	)
+
+	if err := parent.Execute(s); err != nil {
+		t.Fatalf("PIPE shouldn't fail. got=%v", err)
+	}
+
+	if parent.fd(0) == nil || parent.fd(0).write || parent.fd(1) == nil || !parent.fd(1).write {
+		t.Fatalf("PIPE should open the read end at 0 and the write end at 1")
+	}
+
+	child := fork(t, s, parent)
+	pp := parent.fd(0).pipe
+
+	if child.fd(0).pipe != pp || pp.readers != 2 || pp.writers != 2 {
+		t.Fatalf("child should share both ends of the pipe")
+	}
+
+	// Programs can't branch on FORK so give the child its own reader program
+	child.ins = code.Make(code.READ, 0)
+	child.ip = 0
+
+	block(t, s, child)
+
+	if err := parent.Execute(s); err != nil {
+		t.Fatalf("WRITE to a waiting reader shouldn't block. got=%v", err)
+	}
+
+	if child.State != READY || child.acc != 5 || child.ip != 2 {
+		t.Fatalf("child should get the value. got=%d", child.acc)
+	}
+
+	// One fits in the buffer, the next waits for room
+	if err := parent.Execute(s); err != nil {
+		t.Fatalf("WRITE to an empty pipe shouldn't block. got=%v", err)
+	}
+
+	block(t, s, parent)
+
+	if parent.waitingOn != pp || parent.ip != 8 {
+		t.Errorf("parent should wait on the full pipe at its WRITE")
+	}
+}
+
+func TestPipeEndOfFileWhenWritersExit(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	parent := newTestProcess(k, code.Make(code.PIPE), code.Make(code.FORK), code.Make(code.CALC, 1))
+
+	parent.Execute(s)
+	child := fork(t, s, parent)
+
+	// Reader closes its own write end so only the parent writes
+	child.ins = append(code.Make(code.CLOSE, 1), code.Make(code.READ, 0)...)
+	child.ip = 0
+
+	if err := child.Execute(s); err != nil {
+		t.Fatalf("CLOSE shouldn't fail. got=%v", err)
+	}
+
+	block(t, s, child)
+
+	// Last writer exiting closes the write end
+	s.exit(parent)
+
+	if child.State != READY || child.acc != 0 || child.ip != 4 {
+		t.Errorf("reader should get end of file once every writer is gone")
+	}
+}
+
+func TestBlockedWriterGetsBrokenPipe(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	k.PipeSize = 1
+
+	parent := newTestProcess(k, code.Make(code.PIPE), code.Make(code.WRITE, 1, 5), code.Make(code.FORK), code.Make(code.WRITE, 1, 6))
+
+	parent.Execute(s)
+	parent.Execute(s)
+	child := fork(t, s, parent)
+
+	// Child waits in line on the full pipe, the parent is about to
+	block(t, s, child)
+
+	if err := parent.Execute(s); err != ErrBlocked {
+		t.Fatalf("WRITE to a full pipe should block. got=%v", err)
+	}
+
+	// Every read end closes before the parent gets in line
+	k.mu.Lock()
+	k.closeFd(parent, 0)
+	k.closeFd(child, 0)
+	k.mu.Unlock()
+
+	parent.State = WAIT
+	parent.waitingOn.park(parent)
+
+	for _, w := range []*Process{parent, child} {
+		if w.State != READY || w.ip != 5 {
+			t.Fatalf("writer %d should be woken to try the WRITE again. state=%d ip=%d", w.PID, w.State, w.ip)
+		}
+
+		if err := w.Execute(s); err == nil || err.Error() != "broken pipe" {
+			t.Errorf("WRITE with no readers should fail with a broken pipe. got=%v", err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package sched
+
+import (
+	"fmt"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/config"
+)
+
+// SchedulingPolicy : decides which ready process gets the CPU and for how long
+type SchedulingPolicy interface {
+
+	// Next : pick the index of the next process to run from the ready queue, now is the CPU clock
+	Next(readyQ []*Process, now int) int
+
+	// Quantum : number of CPU cycles the process may run before being preempted, 0 means no limit
+	Quantum(p *Process) int
+
+	// OnQuantumExpire : the process used its whole time quantum and is going back to the ready queue
+	OnQuantumExpire(p *Process)
+
+	// OnBlock : the process gave up the CPU to wait on something
+	OnBlock(p *Process)
+
+	// OnExit : the process terminated
+	OnExit(p *Process)
+}
+
+// Preemptor : policies that can take the CPU away from a process before its quantum expires
+type Preemptor interface {
+
+	// Preempt : should the running process go back to the ready queue now
+	Preempt(cur *Process, readyQ []*Process) bool
+}
+
+// NewPolicy : create the scheduling policy selected in the config
+func NewPolicy(conf *config.Sched) (SchedulingPolicy, error) {
+	switch conf.Algorithm {
+	case "rr":
+		return NewRoundRobin(conf.TimeQuantum), nil
+	case "fcfs":
+		return NewFirstComeFirstServe(), nil
+	case "mlfq":
+		if conf.MLFQ == nil {
+			return nil, fmt.Errorf("mlfq needs a MLFQ section in the config")
+		}
+
+		mlfq, err := NewMultilevelFeedbackQueue(conf.MLFQ.Levels, conf.MLFQ.Quanta, conf.MLFQ.BoostInterval)
+		if err != nil {
+			return nil, err
+		}
+
+		return mlfq, nil
+	case "sjf", "srtf":
+		predictor := conf.SJF
+		if predictor == nil {
+			predictor = &config.SJF{}
+		}
+
+		sjf, err := NewShortestJobFirst(conf.Algorithm == "srtf", predictor.Predict, predictor.Alpha, predictor.InitialEstimate)
+		if err != nil {
+			return nil, err
+		}
+
+		return sjf, nil
+	}
+
+	return nil, fmt.Errorf("unknown scheduling algorithm %q", conf.Algorithm)
+}
+
+// RoundRobin : every process gets the CPU for a time quantum in the order they became ready
+type RoundRobin struct {
+	TimeQuantum int // Cycles a process can run before going to the back of the queue
+}
+
+// NewRoundRobin : create round robin policy
+func NewRoundRobin(timeQuantum int) *RoundRobin {
+	return &RoundRobin{
+		TimeQuantum: timeQuantum,
+	}
+}
+
+// Next : front of the ready queue
+func (rr *RoundRobin) Next(readyQ []*Process, now int) int {
+	return 0
+}
+
+// Quantum : same quantum for everyone
+func (rr *RoundRobin) Quantum(p *Process) int {
+	return rr.TimeQuantum
+}
+
+// OnQuantumExpire : nothing to do, the process is already going to the back of the queue
+func (rr *RoundRobin) OnQuantumExpire(p *Process) {}
+
+// OnBlock : nothing to do
+func (rr *RoundRobin) OnBlock(p *Process) {}
+
+// OnExit : nothing to do
+func (rr *RoundRobin) OnExit(p *Process) {}
+
+// FirstComeFirstServe : processes run until they finish in the order they became ready
+type FirstComeFirstServe struct{}
+
+// NewFirstComeFirstServe : create first come first serve policy
+func NewFirstComeFirstServe() *FirstComeFirstServe {
+	return &FirstComeFirstServe{}
+}
+
+// Next : front of the ready queue
+func (f *FirstComeFirstServe) Next(readyQ []*Process, now int) int {
+	return 0
+}
+
+// Quantum : no preemption
+func (f *FirstComeFirstServe) Quantum(p *Process) int {
+	return 0
+}
+
+// OnQuantumExpire : never happens since there's no quantum
+func (f *FirstComeFirstServe) OnQuantumExpire(p *Process) {}
+
+// OnBlock : nothing to do
+func (f *FirstComeFirstServe) OnBlock(p *Process) {}
+
+// OnExit : nothing to do
+func (f *FirstComeFirstServe) OnExit(p *Process) {}
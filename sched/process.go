@@ -1,14 +1,15 @@
 package sched
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
-	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
 	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
 )
 
@@ -30,6 +31,18 @@ const (
 
 	// EXIT : process terminated
 	EXIT
+
+	// ZOMBIE : process terminated and waiting for its parent to take its exit status
+	ZOMBIE
+
+	// SUSPENDED : process swapped out of memory to keep the system from thrashing
+	SUSPENDED
+)
+
+const (
+
+	// PCBSize : bytes of kernel memory a process control block takes
+	PCBSize = 32
 )
 
 var (
@@ -37,53 +50,183 @@ var (
 	// ProcNum : PID for the highest process
 	ProcNum int = 0
 
-	// MailboxAssignment : assigned mailbox
-	mailboxAssignment int = 0
+	// procNumLock : processes are created from many goroutines at once
+	procNumLock sync.Mutex
+
+	// ErrBlocked : the process is waiting on a resource and has to leave the CPU
+	ErrBlocked = errors.New("process blocked")
 )
 
 // Process : Running set of code
 type Process struct {
 	// Some info should be in a process control block
 	// And there will be a list of all process control blocks
-	PID             int    // Process ID
-	Name            string // Process Name
-	State           int    // Process State
-	Runtime         int    // Runtime Requirement
-	Memory          int    // Memory Requirement
-	priority        int    // Priority of the process
-	children        []int    // List of PID to child processes
-	parent          *Process // Parent process
-	ip              int      // Instruction pointer
+	PID             int        // Process ID
+	Name            string     // Process Name
+	State           int        // Process State
+	Runtime         int        // Remaining CALC work
+	Memory          int        // Memory Requirement
+	priority        int        // Priority of the process, 0 is the highest
+	children        []*Process // Child processes that haven't been reaped
+	parent          *Process   // Parent process
+	ip              int        // Instruction pointer
 	ins             code.Instructions
-	pages           []int // memory pages owned by process
-	Critical        bool  // is the process in the critical section
-	assignedMailbox int   // mail affinity
+	pages           int               // Number of virtual pages in the process's page table
+	Segments        []memory.Segment  // Segment table the process was loaded with, nil for one code segment covering its memory
+	Critical        bool              // is the process in the critical section
+	assignedMailbox int               // mail affinity, assigned by the kernel
+	acc             byte              // Accumulator register, holds the last value received or loaded
+	burst           int               // CPU cycles used since the process last blocked
+	estimate        float64           // Predicted length of the next CPU burst, 0 if there's no history yet
+	waitingOn       resource          // What the process is blocked on
+	ioTicks         int               // Ticks of IO left to service
+	relock          bool              // Gave up the kernel lock on CWAIT and needs it back
+	signaled        bool              // Signaled on CWAIT before it finished parking
+	parked          bool              // Parked on CWAIT waiting for a signal
+	killed          int32             // Terminated by the kernel when 1, exits the next time it's on a CPU
+	zombie          bool              // Exited but not reaped yet
+	status          int               // Exit status
+	fds             []*descriptor     // File descriptor table, nil slots are free
+	cowFaults       int               // Writes that had to copy a page shared with another process
+	pageFaults      int               // Addresses it touched that weren't in physical memory
+	faultPage       int               // Page the pager is swapping in for it
+	image           code.Instructions // Program as it was loaded, to roll back to
+	start           int               // Instruction it started at, past the FORK for a child, to roll back to
+	claims          map[int]int       // Most of each resource it will hold at once, nil if it didn't say
+	pcb             int               // Kernel address of its process control block, -1 if it doesn't have one
 }
 
 // CreateProcess : create a new process correctly
 func CreateProcess(name string, runtime int, mem int, ins code.Instructions, insPointer int, parent *Process) *Process {
 
+	procNumLock.Lock()
+
 	// Increment the number of processes that have been created
 	ProcNum++
+	pid := ProcNum
 
-	// Give the process a mailbox assignment
-	mailboxAssignment = (mailboxAssignment + 1) % 10
+	procNumLock.Unlock()
 
 	return &Process{
-		PID:             ProcNum,
-		Name:            name,
-		State:           NEW,
-		Runtime:         runtime,
-		Memory:          mem,
-		priority:        1,
-		children:        []int{},
-		parent:          parent,
-		ip:              insPointer,
-		ins:             ins,
-		pages:           []int{},
-		Critical:        false,
-		assignedMailbox: mailboxAssignment,
+		PID:      pid,
+		Name:     name,
+		State:    NEW,
+		Runtime:  runtime,
+		Memory:   mem,
+		priority: 0,
+		children: []*Process{},
+		parent:   parent,
+		ip:       insPointer,
+		ins:      ins,
+		image:    append(code.Instructions{}, ins...),
+		start:    insPointer,
+		Critical: false,
+		pcb:      -1,
+	}
+}
+
+// ProcessInfo : copy of a process for displaying, safe to read while the process keeps running
+type ProcessInfo struct {
+	PID       int
+	Name      string
+	State     int
+	Runtime   int
+	Memory    int
+	Priority  int
+	COWFaults int
+	Faults    int
+}
+
+// info copies the displayable parts of the process, the caller must hold the lock of the queue it's in
+func (p *Process) info() ProcessInfo {
+	return ProcessInfo{
+		PID:       p.PID,
+		Name:      p.Name,
+		State:     p.State,
+		Runtime:   p.Runtime,
+		Memory:    p.Memory,
+		Priority:  p.priority,
+		COWFaults: p.cowFaults,
+		Faults:    p.pageFaults,
+	}
+}
+
+// codeAddress is the virtual address of the current instruction, -1 if the program isn't in the process's memory
+func (p *Process) codeAddress(mem *memory.Memory) int {
+	if p.pages == 0 {
+		return -1
+	}
+
+	if !mem.Segmented {
+		return p.ip % (p.pages * mem.PageSize)
+	}
+
+	// The program lives in the code segment
+	limit := p.Memory
+	if p.Segments != nil {
+		limit = p.Segments[memory.CodeSegment].Limit
+	}
+
+	if limit == 0 {
+		return -1
+	}
+
+	return memory.SegmentAddress(memory.CodeSegment, p.ip%limit)
+}
+
+// writeCode marks the page holding the current instruction as written to
+func (p *Process) writeCode(mem *memory.Memory) {
+	addr := p.codeAddress(mem)
+	if addr == -1 {
+		return
 	}
+
+	p.writePage(mem, mem.Page(p.PID, addr))
+}
+
+// writePage marks a virtual page of the process as written to, copying it if it's shared
+func (p *Process) writePage(mem *memory.Memory, vpn int) {
+	if mem.Write(p.PID, vpn) {
+		p.cowFaults++
+	}
+}
+
+// translate looks up a virtual address of the process through the CPU's TLB for a kind of access, on a page fault the page
+// is swapped in and ErrBlocked means the process has to wait on the pager for it
+//
+// Any other error means the process can't make that access at all.
+func (p *Process) translate(s *Scheduler, vaddr int, access int) error {
+	k := s.kernel
+
+	var err error
+	if s.TLB != nil {
+		_, err = s.TLB.Translate(p.PID, vaddr, access)
+	} else {
+		_, err = k.Mem.Translate(p.PID, vaddr, access)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if err != memory.ErrPageFault {
+		k.logEvent("process %d: %v at address %d", p.PID, err, vaddr)
+		return err
+	}
+
+	p.pageFaults++
+	vpn := k.Mem.Page(p.PID, vaddr)
+
+	// Nothing to wait for
+	if k.Pager == nil {
+		k.Mem.PageIn(p.PID, vpn)
+		return nil
+	}
+
+	p.faultPage = vpn
+	p.waitingOn = k.Pager
+
+	return ErrBlocked
 }
 
 // String : string representation of process
@@ -91,14 +234,32 @@ func (p *Process) String() string {
 	return fmt.Sprintf("Name: %s, CPU: %d, Memory: %d", p.Name, p.Runtime, p.Memory)
 }
 
-// Execute : execute instruction in process, returns are for system calls (e.g. IO)
-func (p *Process) Execute(cpu *cpu.CPU, mem *memory.Memory, ch chan *Process, mail []chan byte) error {
+// Execute : execute instruction in process on the scheduler's CPU, returns are for system calls (e.g. IO)
+//
+// ErrBlocked means the process has to give up the CPU and wait on p.waitingOn,
+// any other error means the process is finished.
+func (p *Process) Execute(s *Scheduler) error {
+
+	cpu := s.CPU
+	k := s.kernel
+	ch := k.InMsg
 
 	if len(p.ins) <= p.ip {
 		// No more instructions
 		return fmt.Errorf("End of isntructions")
 	}
 
+	// Every instruction is a tick of simulated time, working sets slide along with it
+	k.Mem.Tick()
+
+	// The program lives in the process's pages, so fetching the instruction
+	// has to find its page and can fault. The instruction is retried once the page is in.
+	if addr := p.codeAddress(s.Mem); addr != -1 {
+		if err := p.translate(s, addr, memory.ProtExec); err != nil {
+			return err
+		}
+	}
+
 	// Current instruction to execute
 	curIns := p.ins[p.ip]
 	op := code.Opcode(curIns)
@@ -107,9 +268,22 @@ func (p *Process) Execute(cpu *cpu.CPU, mem *memory.Memory, ch chan *Process, ma
 
 	case code.CALC:
 
+		// Nothing left to calculate
+		if code.ReadUint8(p.ins[p.ip+1:]) == 0 {
+			p.ip += 2
+			break
+		}
+
 		cpu.RunCycle(p.Runtime)
 
-		// Subtract one from the runtime
+		// Keep track of the remaining CALC work and the length of this CPU burst
+		if p.Runtime > 0 {
+			p.Runtime--
+		}
+		p.burst++
+
+		// Subtract one from the runtime, the program lives in the process's pages so this is a write
+		p.writeCode(s.Mem)
 		p.ins[p.ip+1]--
 
 		value := code.ReadUint8(p.ins[p.ip+1:])
@@ -121,60 +295,359 @@ func (p *Process) Execute(cpu *cpu.CPU, mem *memory.Memory, ch chan *Process, ma
 
 		break
 	case code.IO:
+
+		ticks := int(code.ReadUint8(p.ins[p.ip+1:]))
+
 		p.ip += 2
 
-		// Ideally I would want to put the correct values in simulated registered
-		// That way I could just call a generic "system call" instruction that would
-		// Check the registers for the exact system call and parameters
+		// Nothing to wait for
+		if ticks == 0 || len(k.Devices) == 0 {
+			break
+		}
 
-		break
+		// Hand the request to a device and give up the CPU until it's done
+		p.ioTicks = ticks
+		p.waitingOn = k.Devices[p.PID%len(k.Devices)]
+
+		return ErrBlocked
 	case code.FORK:
 
 		p.ip++
 
-		// create child process
-		child := CreateProcess("Fork: "+p.Name, p.Runtime, p.Memory, p.ins, p.ip, p)
+		// create child process with its own copy of the program
+		child := CreateProcess("Fork: "+p.Name, p.Runtime, p.Memory, append(code.Instructions{}, p.ins...), p.ip, p)
+
+		// Child shares the parent's pages and segments until one of them writes
+		child.Segments = p.Segments
+		child.pages = s.Mem.Fork(p.PID, child.PID)
 
-		// Add child process to list of children of parent
-		p.children = append(p.children, child.PID)
+		// Add child process to list of children of parent and share the open descriptors
+		k.mu.Lock()
+		p.children = append(p.children, child)
+		p.inheritFds(child)
+		k.mu.Unlock()
 
 		// Send child to scheduler
 		ch <- child
 
 		break
 	case code.ENTER:
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		if k.overClaims(p, LockKey) {
+			k.logEvent("process %d: ENTER past its resource claims", p.PID)
+			return fmt.Errorf("kernel lock wasn't claimed")
+		}
+
+		// Wait in line if someone else is in the critical section
+		if !k.Lock.acquire(p) {
+			p.waitingOn = k.Lock
+			return ErrBlocked
+		}
+
 		p.ip++
 
-		p.Critical = true
 		break
 	case code.EXIT:
 		p.ip++
 
-		p.Critical = false
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		if err := k.Lock.release(p); err != nil {
+			k.logEvent("process %d: EXIT outside of the critical section", p.PID)
+		}
+
 		break
 	case code.SEND:
 
 		data := p.ins[p.ip+1]
 
+		if len(k.Mailboxes) == 0 {
+			p.ip += 2
+			break
+		}
+
+		mb := k.Mailboxes[p.assignedMailbox]
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		// Wait for room in the mailbox
+		if !mb.send(data) {
+			p.waitingOn = mb
+			return ErrBlocked
+		}
+
 		p.ip += 2
 
-		select {
-		case mail[p.assignedMailbox] <- byte(data):
+		break
+	case code.RECV:
 
-		default:
+		if len(k.Mailboxes) == 0 {
+			p.ip++
 			break
 		}
 
+		mb := k.Mailboxes[p.assignedMailbox]
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		// Wait for a message
+		value, ok := mb.recv()
+		if !ok {
+			p.waitingOn = mb
+			return ErrBlocked
+		}
+
+		// Keep the message where the program can use it
+		p.acc = value
+		p.ip++
+
 		break
-	case code.RECV:
+	case code.SEMINIT:
+
+		id, count := int(p.ins[p.ip+1]), int(p.ins[p.ip+2])
+		p.ip += 3
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		// The first process to initialize the semaphore sets its count
+		if _, ok := k.semaphores[id]; !ok {
+			k.semaphores[id] = InitSemaphore(k, id, count)
+		}
+
+		break
+	case code.WAIT:
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		id := int(p.ins[p.ip+1])
+
+		// Made up with no units, it would never get the count its SEMINIT asks for
+		sem := k.semaphore(id)
+		if sem == nil {
+			k.logEvent("process %d: WAIT %d before SEMINIT", p.PID, id)
+			return fmt.Errorf("semaphore %d wasn't initialized", id)
+		}
+
+		if k.overClaims(p, sem.ID) {
+			k.logEvent("process %d: WAIT %d past its resource claims", p.PID, sem.ID)
+			return fmt.Errorf("semaphore %d wasn't claimed", sem.ID)
+		}
+
+		// Wait in line until someone signals
+		if !sem.wait(p) {
+			p.waitingOn = sem
+			return ErrBlocked
+		}
+
+		p.ip += 2
+
+		break
+	case code.SIGNAL:
+
+		id := int(p.ins[p.ip+1])
+		p.ip += 2
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		sem := k.semaphore(id)
+		if sem == nil {
+			k.logEvent("process %d: SIGNAL %d before SEMINIT", p.PID, id)
+			return fmt.Errorf("semaphore %d wasn't initialized", id)
+		}
+
+		sem.signal(p)
+
+		break
+	case code.CWAIT:
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		c := k.cond(int(p.ins[p.ip+1]))
+		c.wait(p)
+
+		p.waitingOn = c
+		return ErrBlocked
+	case code.CSIGNAL:
+
+		id := int(p.ins[p.ip+1])
+		p.ip += 2
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		k.cond(id).signal()
+
+		break
+	case code.WAITCHILD:
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		status, reaped := k.reap(p)
+
+		// Wait for a child that's still running
+		if !reaped && len(p.children) > 0 {
+			p.waitingOn = k.Children
+			return ErrBlocked
+		}
+
+		// Exit status goes where the program can use it, 0 if there were no children
+		p.acc = byte(status)
+		p.ip++
+
+		break
+	case code.HALT:
+
+		p.status = int(p.ins[p.ip+1])
+		p.ip += 2
+
+		return fmt.Errorf("halted with status %d", p.status)
+	case code.PIPE:
 		p.ip++
 
-		select {
-		case value := <-mail[p.assignedMailbox]:
-			if value < 0 {
-				return fmt.Errorf("[ERROR] error with RECV")
-			}
-		default:
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		k.pipe(p)
+
+		break
+	case code.WRITE:
+
+		fd, value := int(p.ins[p.ip+1]), p.ins[p.ip+2]
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		d := p.fd(fd)
+		if d == nil || !d.write {
+			k.logEvent("process %d: WRITE to bad file descriptor %d", p.PID, fd)
+			p.ip += 3
+			break
+		}
+
+		if d.pipe.readers == 0 {
+			k.logEvent("process %d: WRITE to pipe %d with no readers", p.PID, d.pipe.ID)
+			return fmt.Errorf("broken pipe")
+		}
+
+		// Wait for room in the pipe
+		if !d.pipe.write(value) {
+			p.waitingOn = d.pipe
+			return ErrBlocked
+		}
+
+		p.ip += 3
+
+		break
+	case code.READ:
+
+		fd := int(p.ins[p.ip+1])
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		d := p.fd(fd)
+		if d == nil || d.write {
+			k.logEvent("process %d: READ from bad file descriptor %d", p.PID, fd)
+			p.ip += 2
+			break
+		}
+
+		// Wait for a writer
+		if !d.pipe.read(p) {
+			p.waitingOn = d.pipe
+			return ErrBlocked
+		}
+
+		p.ip += 2
+
+		break
+	case code.CLOSE:
+
+		fd := int(p.ins[p.ip+1])
+		p.ip += 2
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		if err := k.closeFd(p, fd); err != nil {
+			k.logEvent("process %d: CLOSE of %v", p.PID, err)
+		}
+
+		break
+	case code.LOAD, code.STORE:
+
+		addr := int(code.ReadUint16(p.ins[p.ip+1:]))
+
+		access := memory.ProtRead
+		if op == code.STORE {
+			access = memory.ProtWrite
+		}
+
+		// Wait for the page and retry, or give up on an address it can't use
+		if err := p.translate(s, addr, access); err != nil {
+			return err
+		}
+
+		// STORE writes the accumulator, LOAD reads into it
+		var err error
+		if op == code.STORE {
+			p.writePage(s.Mem, s.Mem.Page(p.PID, addr))
+			err = s.Mem.Store(p.PID, addr, p.acc)
+		} else {
+			p.acc, err = s.Mem.Load(p.PID, addr)
+		}
+
+		// Another CPU took the page in between, try again
+		if err == memory.ErrPageFault {
+			break
+		}
+
+		if err != nil {
+			k.logEvent("process %d: %v at address %d", p.PID, err, addr)
+			return err
+		}
+
+		p.ip += 3
+
+		break
+	case code.SHMGET:
+
+		key, size := int(p.ins[p.ip+1]), int(code.ReadUint16(p.ins[p.ip+2:]))
+		p.ip += 4
+
+		if err := k.Mem.SharedGet(key, size); err != nil {
+			k.logEvent("process %d: SHMGET %d: %v", p.PID, key, err)
+		}
+
+		break
+	case code.SHMAT:
+
+		key := int(p.ins[p.ip+1])
+		p.ip += 2
+
+		if err := k.Mem.Attach(p.PID, key); err != nil {
+			k.logEvent("process %d: SHMAT %d: %v", p.PID, key, err)
+		}
+
+		break
+	case code.SHMDT:
+
+		key := int(p.ins[p.ip+1])
+		p.ip += 2
+
+		if err := k.Mem.Detach(p.PID, key); err != nil {
+			k.logEvent("process %d: SHMDT %d: %v", p.PID, key, err)
 		}
 
 		break
@@ -190,13 +663,22 @@ func (p *Process) Execute(cpu *cpu.CPU, mem *memory.Memory, ch chan *Process, ma
 }
 
 // CreateRandomProcessFromTemplate : Jitter template values to create custom processes
-func CreateRandomProcessFromTemplate(templateName string, memory int, instructions [][]string, ch chan *Process) {
+func CreateRandomProcessFromTemplate(templateName string, mem int, claims map[int]int, segments []memory.Segment, instructions [][]string, ch chan *Process) {
 
-	r := rand.New(rand.NewSource(time.Now().Unix()))
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// Jitter a copy so processes made from the same template at the same time don't share it
+	template := make([][]string, len(instructions))
+	for i, instruction := range instructions {
+		template[i] = append([]string{}, instruction...)
+	}
+	instructions = template
 
 	totalRuntime := 0
 	for _, instruction := range instructions {
-		if len(instruction) < 2 {
+
+		// Only work amounts get jittered, the rest are keys and values programs agree on
+		if len(instruction) < 2 || (instruction[0] != "CALC" && instruction[0] != "IO") {
 			continue
 		}
 
@@ -223,7 +705,9 @@ func CreateRandomProcessFromTemplate(templateName string, memory int, instructio
 	// Convert memory heavy 2d string array to dense byte array
 	program := code.Assemble(instructions)
 
-	p := CreateProcess("From template: "+templateName, totalRuntime, memory, program, 0, nil)
+	p := CreateProcess("From template: "+templateName, totalRuntime, mem, program, 0, nil)
+	p.claims = claims
+	p.Segments = segments
 
 	// Send process to the scheduler
 	ch <- p
@@ -1 +1,450 @@
 package sched
+
+import (
+	"testing"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
+
+// newTestProcess registers a process running the program with the kernel
+func newTestProcess(k *Kernel, program ...[]byte) *Process {
+	ins := code.Instructions{}
+	for _, instruction := range program {
+		ins = append(ins, instruction...)
+	}
+
+	p := CreateProcess("test", 0, 32, ins, 0, nil)
+	k.register(p)
+
+	return p
+}
+
+// block executes the process and parks it like the scheduler would if it blocks
+func block(t *testing.T, s *Scheduler, p *Process) {
+	if err := p.Execute(s); err != ErrBlocked {
+		t.Fatalf("process should block. got=%v", err)
+	}
+
+	p.State = WAIT
+	p.waitingOn.park(p)
+}
+
+func TestRecvBlocksUntilSend(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	mb := k.AddMailbox(2)
+
+	receiver := newTestProcess(k, code.Make(code.RECV))
+	sender := newTestProcess(k, code.Make(code.SEND, 7))
+	receiver.assignedMailbox, sender.assignedMailbox = mb.ID, mb.ID
+
+	block(t, s, receiver)
+
+	if receiver.waitingOn != mb || receiver.ip != 0 {
+		t.Fatalf("receiver should wait on the mailbox at its RECV")
+	}
+
+	if err := sender.Execute(s); err != nil {
+		t.Fatalf("send shouldn't fail. got=%v", err)
+	}
+
+	if receiver.State != READY || len(s.ReadyQ) != 1 {
+		t.Fatalf("receiver should be woken up by the send")
+	}
+
+	if receiver.acc != 7 {
+		t.Errorf("receiver should have the message. want=7, got=%d", receiver.acc)
+	}
+
+	if receiver.ip != 1 {
+		t.Errorf("receiver should be past its RECV. want=1, got=%d", receiver.ip)
+	}
+
+	if mb.Len() != 0 {
+		t.Errorf("message should go straight to the receiver. got=%d messages", mb.Len())
+	}
+}
+
+func TestSendBlocksWhenFull(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	mb := k.AddMailbox(1)
+
+	sender := newTestProcess(k, code.Make(code.SEND, 1), code.Make(code.SEND, 2))
+	receiver := newTestProcess(k, code.Make(code.RECV))
+	sender.assignedMailbox, receiver.assignedMailbox = mb.ID, mb.ID
+
+	if err := sender.Execute(s); err != nil {
+		t.Fatalf("first send shouldn't block. got=%v", err)
+	}
+
+	block(t, s, sender)
+
+	if err := receiver.Execute(s); err != nil {
+		t.Fatalf("recv shouldn't fail. got=%v", err)
+	}
+
+	if receiver.acc != 1 {
+		t.Errorf("receiver should get the oldest message. want=1, got=%d", receiver.acc)
+	}
+
+	if sender.State != READY {
+		t.Fatalf("sender should be woken up once there's room")
+	}
+
+	if mb.Len() != 1 || mb.messages[0] != 2 {
+		t.Errorf("blocked message should be delivered. got=%v", mb.messages)
+	}
+}
+
+func TestEnterBlocksWhileLockHeld(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	first := newTestProcess(k, code.Make(code.ENTER), code.Make(code.EXIT))
+	second := newTestProcess(k, code.Make(code.ENTER), code.Make(code.EXIT))
+
+	if err := first.Execute(s); err != nil || !first.Critical {
+		t.Fatalf("first process should enter the critical section. got=%v", err)
+	}
+
+	block(t, s, second)
+
+	if second.waitingOn != k.Lock || second.Critical {
+		t.Fatalf("second process should wait for the lock")
+	}
+
+	// EXIT hands the lock to the next in line
+	if err := first.Execute(s); err != nil {
+		t.Fatalf("exit shouldn't fail. got=%v", err)
+	}
+
+	if first.Critical {
+		t.Errorf("first process should have left the critical section")
+	}
+
+	if owner, _ := k.Lock.Owner(); owner != second.PID {
+		t.Errorf("lock should be handed over. want=%d, got=%d", second.PID, owner)
+	}
+
+	if !second.Critical || second.State != READY || second.ip != 1 {
+		t.Errorf("second process should be woken inside the critical section")
+	}
+}
+
+func TestExitingWhileHoldingLock(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	p := newTestProcess(k, code.Make(code.ENTER))
+
+	s.runProcess(p)
+
+	if p.State != EXIT {
+		t.Fatalf("process should have exited. got=%d", p.State)
+	}
+
+	if _, locked := k.Lock.Owner(); locked {
+		t.Errorf("lock should be released when its owner exits")
+	}
+
+	if len(k.Events()) != 1 {
+		t.Errorf("exiting while holding the lock should be logged. got=%v", k.Events())
+	}
+}
+
+func TestSemaphoreBlocksUntilSignal(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	first := newTestProcess(k, code.Make(code.SEMINIT, 4, 1), code.Make(code.WAIT, 4), code.Make(code.SIGNAL, 4))
+	second := newTestProcess(k, code.Make(code.SEMINIT, 4, 9), code.Make(code.WAIT, 4))
+
+	for i := 0; i < 2; i++ {
+		if err := first.Execute(s); err != nil {
+			t.Fatalf("first process shouldn't block. got=%v", err)
+		}
+	}
+
+	// Semaphore already exists so the second SEMINIT doesn't reset it
+	if err := second.Execute(s); err != nil {
+		t.Fatalf("SEMINIT shouldn't fail. got=%v", err)
+	}
+
+	block(t, s, second)
+
+	if second.waitingOn != k.semaphores[4] || second.ip != 3 {
+		t.Fatalf("second process should wait on the semaphore at its WAIT")
+	}
+
+	if err := first.Execute(s); err != nil {
+		t.Fatalf("signal shouldn't fail. got=%v", err)
+	}
+
+	if second.State != READY || second.ip != 5 {
+		t.Fatalf("second process should be woken past its WAIT")
+	}
+
+	if count := k.semaphores[4].Count(); count != 0 {
+		t.Errorf("unit should go straight to the waiter. want=0, got=%d", count)
+	}
+}
+
+func TestWaitBeforeSeminitFails(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	consumer := newTestProcess(k, code.Make(code.WAIT, 3))
+	signaler := newTestProcess(k, code.Make(code.SIGNAL, 3))
+	producer := newTestProcess(k, code.Make(code.SEMINIT, 3, 1))
+
+	// Nobody created the semaphore yet
+	if err := consumer.Execute(s); err == nil || err == ErrBlocked {
+		t.Fatalf("WAIT before SEMINIT should fail. got=%v", err)
+	}
+
+	if err := signaler.Execute(s); err == nil {
+		t.Fatalf("SIGNAL before SEMINIT should fail")
+	}
+
+	if err := producer.Execute(s); err != nil {
+		t.Fatalf("SEMINIT shouldn't fail. got=%v", err)
+	}
+
+	if count := k.semaphores[3].Count(); count != 1 {
+		t.Errorf("SEMINIT should set the count after a failed WAIT. want=1, got=%d", count)
+	}
+}
+
+func TestCondGivesUpKernelLock(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	waiter := newTestProcess(k, code.Make(code.ENTER), code.Make(code.CWAIT, 1), code.Make(code.EXIT))
+	signaler := newTestProcess(k, code.Make(code.ENTER), code.Make(code.CSIGNAL, 1), code.Make(code.EXIT))
+
+	if err := waiter.Execute(s); err != nil {
+		t.Fatalf("ENTER shouldn't block. got=%v", err)
+	}
+
+	block(t, s, waiter)
+
+	if owner, locked := k.Lock.Owner(); locked {
+		t.Fatalf("CWAIT should give up the kernel lock. owner=%d", owner)
+	}
+
+	// Signaler gets the lock and wakes the waiter, who has to wait for the lock again
+	for i := 0; i < 2; i++ {
+		if err := signaler.Execute(s); err != nil {
+			t.Fatalf("signaler shouldn't block. got=%v", err)
+		}
+	}
+
+	if waiter.waitingOn != k.Lock || waiter.State != WAIT {
+		t.Fatalf("waiter should be back in line for the kernel lock")
+	}
+
+	if err := signaler.Execute(s); err != nil {
+		t.Fatalf("EXIT shouldn't fail. got=%v", err)
+	}
+
+	if owner, _ := k.Lock.Owner(); owner != waiter.PID || !waiter.Critical {
+		t.Fatalf("waiter should get the kernel lock back")
+	}
+
+	if waiter.State != READY || waiter.ip != 3 {
+		t.Errorf("waiter should be woken past its CWAIT. ip=%d", waiter.ip)
+	}
+}
+
+func TestForkCopiesProgramAndSharesPages(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	parent := newTestProcess(k, code.Make(code.FORK), code.Make(code.CALC, 5))
+	s.admit(parent)
+
+	child := fork(t, s, parent)
+
+	if child.pages != parent.pages || k.Mem.Shared() != parent.pages {
+		t.Fatalf("child should share the parent's pages")
+	}
+
+	// Child's CALC copies the page and leaves the parent's program alone
+	if err := child.Execute(s); err != nil {
+		t.Fatalf("CALC shouldn't fail. got=%v", err)
+	}
+
+	if parent.ins[2] != 5 || child.ins[2] != 4 {
+		t.Errorf("CALC in the child shouldn't change the parent. parent=%d child=%d", parent.ins[2], child.ins[2])
+	}
+
+	if child.cowFaults != 1 || k.Mem.Shared() != parent.pages-1 || k.Mem.COWFaults() != 1 {
+		t.Errorf("child's first write should be a copy-on-write fault")
+	}
+}
+
+func TestPageFaultBlocksOnPager(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	k.AddPager(0)
+
+	p := newTestProcess(k, code.Make(code.LOAD, 40), code.Make(code.STORE, 70))
+	p.Memory = 64
+	p.pages = k.Mem.Add(p.Memory, p.PID)
+
+	// Fetching the instruction faults on the first page, then the LOAD on the second
+	for _, page := range []int{0, 1} {
+		if err := p.Execute(s); err != ErrBlocked {
+			t.Fatalf("touching page %d should fault. got=%v", page, err)
+		}
+
+		if p.waitingOn != k.Pager || p.faultPage != page || p.ip != 0 {
+			t.Fatalf("process should wait on the pager for page %d at its LOAD. got page=%d ip=%d", page, p.faultPage, p.ip)
+		}
+
+		k.Mem.PageIn(p.PID, p.faultPage)
+	}
+
+	if err := p.Execute(s); err != nil {
+		t.Fatalf("LOAD should go through once its pages are in. got=%v", err)
+	}
+
+	if p.ip != 3 || p.pageFaults != 2 {
+		t.Errorf("wrong state after LOAD. ip=%d faults=%d", p.ip, p.pageFaults)
+	}
+
+	// Address 70 is past the two pages the process has
+	if err := p.Execute(s); err == nil || err == ErrBlocked {
+		t.Errorf("STORE outside of the process's pages should terminate it. got=%v", err)
+	}
+}
+
+func TestSegmentedProcess(t *testing.T) {
+	k := newTestKernel()
+	k.Mem.Segmented = true
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	data := memory.SegmentAddress(memory.DataSegment, 20)
+	p := newTestProcess(k, code.Make(code.STORE, data), code.Make(code.STORE, 0))
+	p.Segments, _ = memory.ParseSegments([]string{"code:32", "data:32"})
+	s.admit(p)
+
+	// Fetching from the code segment and writing the data segment is fine
+	if err := p.Execute(s); err != nil || p.ip != 3 || p.pageFaults != 2 {
+		t.Fatalf("STORE to the data segment should go through. err=%v ip=%d faults=%d", err, p.ip, p.pageFaults)
+	}
+
+	if !k.Mem.PageTable(p.PID)[1].Dirty {
+		t.Errorf("data segment's page should be written")
+	}
+
+	// Code isn't writable
+	if err := p.Execute(s); err != memory.ErrProtection {
+		t.Errorf("STORE to the code segment should terminate the process. got=%v", err)
+	}
+}
+
+func TestSharedMemoryBetweenProcesses(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	shm := memory.SharedAddress(1, 8)
+	writer := newTestProcess(k, code.Make(code.SHMGET, 1, 64), code.Make(code.SHMAT, 1), code.Make(code.STORE, shm), code.Make(code.SHMDT, 1))
+	reader := newTestProcess(k, code.Make(code.SHMAT, 1), code.Make(code.LOAD, shm))
+	s.admit(writer)
+	s.admit(reader)
+
+	for i := 0; i < 3; i++ {
+		if err := writer.Execute(s); err != nil {
+			t.Fatalf("writer shouldn't fail. got=%v", err)
+		}
+	}
+
+	if writer.cowFaults != 0 {
+		t.Errorf("STORE to shared memory shouldn't copy the page")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := reader.Execute(s); err != nil {
+			t.Fatalf("reader shouldn't fail. got=%v", err)
+		}
+	}
+
+	a, _ := k.Mem.Translate(writer.PID, shm, memory.ProtRead)
+	b, _ := k.Mem.Translate(reader.PID, shm, memory.ProtRead)
+	if a != b {
+		t.Errorf("both processes should reach the same frame. writer=%d reader=%d", a, b)
+	}
+
+	// Segment stays while the reader is attached
+	if err := writer.Execute(s); err != nil {
+		t.Fatalf("SHMDT shouldn't fail. got=%v", err)
+	}
+
+	if segments := k.Mem.SharedSegments(); len(segments) != 1 || segments[0].Attached != 1 {
+		t.Errorf("reader should still be attached. got=%v", segments)
+	}
+}
+
+func TestStoreSurvivesSwapOut(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	p := newTestProcess(k, code.Make(code.STORE, 20), code.Make(code.LOAD, 20))
+	s.admit(p)
+
+	p.acc = 42
+	if err := p.Execute(s); err != nil {
+		t.Fatalf("STORE shouldn't fail. got=%v", err)
+	}
+
+	if out := k.Mem.SwapOut(p.PID); out != 1 {
+		t.Fatalf("the process's page should leave memory. got=%d", out)
+	}
+
+	p.acc = 0
+	if err := p.Execute(s); err != nil {
+		t.Fatalf("LOAD shouldn't fail. got=%v", err)
+	}
+
+	if p.acc != 42 {
+		t.Errorf("LOAD should read back what STORE wrote. got=%d", p.acc)
+	}
+}
+
+func TestForkedChildGetsContiguousContents(t *testing.T) {
+	k := newTestKernel()
+	k.Mem.AddContiguous("first", false)
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	parent := newTestProcess(k, code.Make(code.STORE, 20), code.Make(code.FORK), code.Make(code.LOAD, 20))
+	s.admit(parent)
+
+	parent.acc = 42
+	if err := parent.Execute(s); err != nil {
+		t.Fatalf("STORE shouldn't fail. got=%v", err)
+	}
+
+	child := fork(t, s, parent)
+
+	// The parent writes again after the fork, the child keeps what it had
+	if err := k.Mem.Store(parent.PID, 20, 7); err != nil {
+		t.Fatalf("parent should still write to its region. got=%v", err)
+	}
+
+	s.admit(child)
+	if child.State != READY {
+		t.Fatalf("child should be placed in a region of its own")
+	}
+
+	if err := child.Execute(s); err != nil {
+		t.Fatalf("LOAD shouldn't fail. got=%v", err)
+	}
+
+	if child.acc != 42 {
+		t.Errorf("child should read what the parent stored before the fork. got=%d", child.acc)
+	}
+}
@@ -0,0 +1,27 @@
+package sched
+
+import "fmt"
+
+// resource : something a process can block on until the kernel wakes it up
+type resource interface {
+
+	// park queues a blocked process on the resource, if the resource became
+	// available in the meantime the process is woken right away
+	park(p *Process)
+}
+
+// waitable : resource guarded by the kernel lock that only other processes can make available,
+// so processes can end up waiting on it forever
+type waitable interface {
+	resource
+	fmt.Stringer
+
+	// waiting : processes parked on the resource, the kernel lock must be held
+	waiting() []*Process
+
+	// wakes : could q wake p up running its program from instruction from, the kernel lock must be held
+	wakes(p *Process, q *Process, from int) bool
+
+	// cancel : take a parked process out of line, the kernel lock must be held
+	cancel(p *Process)
+}
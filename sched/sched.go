@@ -2,204 +2,318 @@ package sched
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
 	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
 	"github.com/jonaylor89/John_Naylor_CMSC312_2019/utils"
 )
 
+const (
+
+	// SchedulerCache : name of the slab cache schedulers' bookkeeping is allocated from
+	SchedulerCache = "scheduler"
+
+	// SchedulerSize : bytes of kernel memory a scheduler's bookkeeping takes
+	SchedulerSize = 64
+)
+
 // Scheduler : manager for resources and controller to schedule process to run
 type Scheduler struct {
-	CPU               *cpu.CPU       // CPU the scheduler is assigned
-	Mem               *memory.Memory // Memory module the scheduler is assigned
-	InMsg             chan *Process  // Message channel where scheduler receives processes
-	ReadyQ            []*Process     // Ready Queue for processes
-	WaitingQ          []*Process     // Waiting Queue for processes
-	MinimumFreeFrames int            // Minimum number of frames for a process to be made ready
-	TimeQuantum       int            // Time quantum for a process using round robin
-	Mailboxes         []chan byte    // Mailboxes for interprocess communication
+	ID                int              // Index of the scheduler in the kernel
+	CPU               *cpu.CPU         // CPU the scheduler is assigned
+	Mem               *memory.Memory   // Memory module the scheduler is assigned
+	ReadyQ            []*Process       // Ready Queue for processes
+	WaitingQ          []*Process       // Waiting Queue for processes
+	MinimumFreeFrames int              // Minimum number of frames for a process to be made ready
+	Policy            SchedulingPolicy // Decides which process runs next and for how long
+	TLB               *memory.TLB      // Translation lookaside buffer of the CPU, nil to walk page tables every time
+
+	kernel  *Kernel    // Kernel the scheduler belongs to
+	block   int        // Kernel address of its bookkeeping, -1 if it doesn't have any
+	running *Process   // Process on the CPU
+	mu      sync.Mutex // Guards the queues and the running process
 }
 
-// InitScheduler : create new scheduler
-func InitScheduler(cpu *cpu.CPU, mem *memory.Memory, in chan *Process, minimumFreeFrames int, timeQuantum int) *Scheduler {
+// InitScheduler : create new scheduler for a CPU in the kernel
+func InitScheduler(k *Kernel, id int, cpu *cpu.CPU, policy SchedulingPolicy) *Scheduler {
 
-	s := &Scheduler{
+	// Kernel memory for the queues and the rest of the bookkeeping
+	block := -1
+	if cache := k.Mem.Cache(SchedulerCache); cache != nil {
+		block, _ = cache.Alloc()
+	}
+
+	return &Scheduler{
+		ID:                id,
 		CPU:               cpu,
-		Mem:               mem,
-		InMsg:             in,
+		Mem:               k.Mem,
 		ReadyQ:            []*Process{},
 		WaitingQ:          []*Process{},
-		MinimumFreeFrames: minimumFreeFrames,
-		TimeQuantum:       timeQuantum,
-		Mailboxes: []chan byte{
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-			make(chan byte, 10),
-		},
+		MinimumFreeFrames: k.MinimumFreeFrames,
+		Policy:            policy,
+		kernel:            k,
+		block:             block,
 	}
-
-	// start checking for new processes
-	go s.recvProc()
-
-	return s
 }
 
-// RunRoundRobin : Start the schedule and process execution
-func (s *Scheduler) RunRoundRobin() {
+// Run : Start the scheduler and process execution
+func (s *Scheduler) Run() {
 
 	// event loop
 	for {
 
-		// Loop through processes backwards and execute them off a time quantum
-		for i := len(s.ReadyQ) - 1; i > 0; i-- {
-			curProc := s.ReadyQ[i]
-
-			curProc.State = RUN
-
-			timeNull := s.CPU.TotalCycles
+		select {
+		case <-s.kernel.quit:
+			return
+		default:
+		}
 
-			// Only get so many CPU cycles
-			for s.CPU.TotalCycles-timeNull < s.TimeQuantum && !curProc.Critical {
+		s.mu.Lock()
 
-				// Give the process access to the CPU and Process Channel
-				err := curProc.Execute(s.CPU, s.Mem, s.InMsg, s.Mailboxes)
-				if err != nil {
+		// Check if waiting processes can be moved to ready
+		s.assessWaiting()
 
-					curProc.State = EXIT
-					s.ReadyQ = remove(s.ReadyQ, i)
-					s.Mem.RemovePages(curProc.PID)
-					break
-				}
+		if len(s.ReadyQ) == 0 {
+			s.mu.Unlock()
 
-				curProc.State = READY
+			// Nothing to run here so try to pull work from a busier CPU
+			if !s.kernel.pull(s) {
+				runtime.Gosched()
 			}
 
+			continue
 		}
 
-		// Check if waiting processes can be moved to ready
-		s.assessWaiting()
+		// Let the policy pick who goes next and pop them from the ready queue
+		i := s.Policy.Next(s.ReadyQ, s.CPU.TotalCycles)
+		curProc := s.ReadyQ[i]
+		s.ReadyQ = remove(s.ReadyQ, i)
+		s.running = curProc
 
+		s.mu.Unlock()
+
+		s.runProcess(curProc)
 	}
 }
 
-// RunFirstComeFirstServe : First come first serve algorithm
-func (s *Scheduler) RunFirstComeFirstServe() {
+// Load : number of processes this scheduler is responsible for running
+func (s *Scheduler) Load() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for {
+	return s.load()
+}
 
-		if len(s.ReadyQ) > 0 {
+// load is Load without locking
+func (s *Scheduler) load() int {
+	if s.running != nil {
+		return len(s.ReadyQ) + 1
+	}
 
-			var curProc *Process
+	return len(s.ReadyQ)
+}
 
-			// Pop from ready queue
-			curProc, s.ReadyQ = s.ReadyQ[0], s.ReadyQ[1:]
+// ReadySnapshot : copy of the ready queue
+func (s *Scheduler) ReadySnapshot() []ProcessInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-			curProc.State = RUN
+	return snapshot(s.ReadyQ)
+}
 
-			// Execute process until it terminates
-			for {
+// WaitingSnapshot : copy of the waiting queue
+func (s *Scheduler) WaitingSnapshot() []ProcessInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-				// Execute instruction
-				err := curProc.Execute(s.CPU, s.Mem, s.InMsg, s.Mailboxes)
-				if err != nil {
-					curProc.State = EXIT
-					break
-				}
+	return snapshot(s.WaitingQ)
+}
 
-				// Is no more runtime, terminate process
-				if curProc.Runtime <= 0 {
-					curProc.State = EXIT
-					break
-				}
-			}
+// snapshot copies a queue, the caller must hold the lock for it
+func snapshot(queue []*Process) []ProcessInfo {
+	infos := make([]ProcessInfo, len(queue))
+	for i, p := range queue {
+		infos[i] = p.info()
+	}
 
-		}
+	return infos
+}
 
-		// Check if waiting processes can be moved to ready
-		s.assessWaiting()
+// runProcess gives the process the CPU until it exits or its quantum runs out
+func (s *Scheduler) runProcess(curProc *Process) {
+
+	curProc.State = RUN
 
+	// Context switch
+	if s.TLB != nil {
+		s.TLB.Switch(curProc.PID)
 	}
-}
 
-// look through the waiting queue and see if any processes are ready
-func (s *Scheduler) assessWaiting() {
-	for i := len(s.WaitingQ) - 1; i > 0; i-- {
-		proc := s.WaitingQ[i]
+	quantum := s.Policy.Quantum(curProc)
+	timeNull := s.CPU.TotalCycles
+
+	preemptor, canPreempt := s.Policy.(Preemptor)
+
+	for {
+
+		// Kernel terminated the process
+		if curProc.isKilled() {
+			s.exit(curProc)
+			return
+		}
 
-		if s.memoryCheck() {
-			s.WaitingQ = remove(s.WaitingQ, i)
+		// Give the process access to the CPU and kernel
+		err := curProc.Execute(s)
 
-			proc.State = READY
+		// Wait on whatever the process needs off the CPU
+		if err == ErrBlocked {
 
-			s.ReadyQ = append(s.ReadyQ, proc)
+			s.mu.Lock()
+			s.Policy.OnBlock(curProc)
+			curProc.burst = 0
+			s.running = nil
+			s.mu.Unlock()
 
-			s.Mem.Add(proc.Memory, proc.PID)
+			curProc.State = WAIT
+			curProc.waitingOn.park(curProc)
+			return
 		}
+
+		if err != nil {
+			s.exit(curProc)
+			return
+		}
+
+		// Processes in the critical section can't be preempted
+		if curProc.Critical {
+			continue
+		}
+
+		s.mu.Lock()
+
+		// Only get so many CPU cycles
+		if quantum > 0 && s.CPU.TotalCycles-timeNull >= quantum {
+
+			curProc.State = READY
+			s.Policy.OnQuantumExpire(curProc)
+			s.ReadyQ = append(s.ReadyQ, curProc)
+			s.running = nil
+			s.mu.Unlock()
+			return
+		}
+
+		// Let the policy kick the process off early
+		if canPreempt && preemptor.Preempt(curProc, s.ReadyQ) {
+
+			curProc.State = READY
+			s.ReadyQ = append(s.ReadyQ, curProc)
+			s.running = nil
+			s.mu.Unlock()
+			return
+		}
+
+		s.mu.Unlock()
 	}
 }
 
-// Check if more than the minimum free frames are available
-func (s *Scheduler) memoryCheck() bool {
-	if cap(s.Mem.PhysicalMemory)-len(s.Mem.PhysicalMemory) > s.MinimumFreeFrames {
-		return true
+// admit takes a new process from the kernel and puts it in the ready or waiting queue
+func (s *Scheduler) admit(p *Process) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Memory lays the process out by its segments
+	if p.Segments != nil && p.pages == 0 {
+		s.Mem.SetSegments(p.PID, p.Segments)
 	}
 
-	return false
-}
+	if s.memoryCheck(p) {
 
-// recvProc keeps an eye on the process channel
-func (s *Scheduler) recvProc() {
+		// If memory available then set to READY
+		p.State = READY
 
-	for {
+		// New process ready to be executed
+		s.ReadyQ = append(s.ReadyQ, p)
 
-		// Checks for new processes to schedule
-		select {
-		case x, ok := <-s.InMsg:
-			if ok {
+	} else {
+		// If memory not available then set to WAIT
+		p.State = WAIT
 
-				if s.memoryCheck() {
+		// New process waiting for memory
+		s.WaitingQ = append(s.WaitingQ, p)
+	}
 
-					// If memory available then set to READY
-					x.State = READY
+	// Forked processes already share their parent's pages, and contiguous memory is placed once there's room
+	if p.pages == 0 && s.Mem.Contiguous == nil {
+		p.pages = s.Mem.Add(p.Memory, p.PID)
+	}
+}
 
-					// New process ready to be executed
-					s.ReadyQ = append(s.ReadyQ, x)
+// exit cleans up after a process that finished
+func (s *Scheduler) exit(p *Process) {
+	if p.isKilled() {
+		p.status = StatusKilled
+	}
 
-				} else {
-					// If memory not available then set to WAIT
-					x.State = WAIT
+	s.mu.Lock()
+	p.State = EXIT
+	s.Policy.OnExit(p)
+	s.running = nil
+	s.mu.Unlock()
 
-					// New process waiting for memory
-					s.WaitingQ = append(s.WaitingQ, x)
+	s.kernel.releaseAll(p)
+	s.Mem.RemovePages(p.PID)
+	s.kernel.unregister(p)
+}
 
-				}
+// ready puts a process back in the ready queue after it was blocked
+func (s *Scheduler) ready(p *Process) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-				x.pages = s.Mem.Add(x.Memory, x.PID)
+	p.State = READY
 
-			} else {
-				// Channel is closed to execution must exit
-				return
-			}
-		default:
-			// No new processes
-			break
-		}
+	s.ReadyQ = append(s.ReadyQ, p)
+}
 
+// look through the waiting queue and see if any processes are ready
+func (s *Scheduler) assessWaiting() {
+	for len(s.WaitingQ) > 0 && s.memoryCheck(s.WaitingQ[0]) {
+		proc := s.WaitingQ[0]
+		s.WaitingQ = remove(s.WaitingQ, 0)
+
+		proc.State = READY
+
+		s.ReadyQ = append(s.ReadyQ, proc)
+	}
+}
+
+// Check if more than the minimum free frames are available, with contiguous memory
+// the process is placed in a hole if one fits it
+func (s *Scheduler) memoryCheck(p *Process) bool {
+	if s.Mem.Contiguous == nil {
+		return s.Mem.FreeFrames() > s.MinimumFreeFrames
 	}
+
+	pages, err := s.Mem.Place(p.PID, p.Memory)
+	if err != nil {
+		return false
+	}
+
+	p.pages = pages
+
+	return true
 }
 
-// LoadTemplate : load in template process and create process mutations off of it
-func LoadTemplate(filename string, numOfProcesses int, processChan chan *Process) error {
+// LoadTemplate : load in template process and create process mutations off of it, segment:offset
+// addresses are turned into plain offsets when memory isn't segmented
+func LoadTemplate(filename string, numOfProcesses int, segmented bool, processChan chan *Process) error {
 
 	f, err := os.Open(filename)
 	if err != nil {
@@ -221,6 +335,12 @@ func LoadTemplate(filename string, numOfProcesses int, processChan chan *Process
 	procMemoryField, _ := utils.ReadLine(reader)
 	procMemory, _ := strconv.Atoi(procMemoryField[1])
 
+	// Resources the processes will claim at most, if the template says
+	var claims map[int]int
+
+	// Segment layout of the processes, if the template says
+	var segments []memory.Segment
+
 	// Loop through template file from the instructions
 	for {
 
@@ -234,6 +354,52 @@ func LoadTemplate(filename string, numOfProcesses int, processChan chan *Process
 			break
 		}
 
+		if len(instruction) != 0 && instruction[0] == "Resources:" {
+			claims, err = ParseClaims(instruction[1:])
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if len(instruction) != 0 && instruction[0] == "Segments:" {
+			segments, err = memory.ParseSegments(instruction[1:])
+			if err != nil {
+				return err
+			}
+
+			// The segments are all the memory the processes get
+			procMemory = 0
+			for _, seg := range segments {
+				procMemory += seg.Limit
+			}
+
+			continue
+		}
+
+		// Addresses can be given as segment:offset
+		if len(instruction) > 1 && (instruction[0] == "LOAD" || instruction[0] == "STORE") {
+			addr, err := memory.ParseAddress(instruction[1])
+			if err != nil {
+				return err
+			}
+
+			// Without segmentation the segments are laid out one after another
+			if !segmented && strings.Contains(instruction[1], ":") && !strings.HasPrefix(instruction[1], "shm:") {
+				layout := segments
+				if layout == nil {
+					layout = []memory.Segment{{Name: memory.Segments[memory.CodeSegment], Limit: procMemory}}
+				}
+
+				if addr, err = memory.FlatAddress(layout, addr); err != nil {
+					return fmt.Errorf("address %q is past the end of its segment", instruction[1])
+				}
+			}
+
+			instruction[1] = strconv.Itoa(addr)
+		}
+
 		if len(instruction) != 0 {
 
 			instructions = append(instructions, instruction)
@@ -244,16 +410,28 @@ func LoadTemplate(filename string, numOfProcesses int, processChan chan *Process
 	// utils.ShuffleInstructions(instructions)
 
 	for i := 0; i < numOfProcesses; i++ {
-		go CreateRandomProcessFromTemplate(procName, procMemory, instructions, processChan)
+		go CreateRandomProcessFromTemplate(procName, procMemory, claims, segments, instructions, processChan)
 	}
 
 	return nil
 }
 
+// remove keeps the order of the rest of the slice so policies can rely on arrival order
 func remove(slice []*Process, s int) []*Process {
-	slice[s] = slice[len(slice)-1] // Copy last element to index i.
-	// slice[len(slice)-1] = nil   	// Erase last element (write zero value)
+	copy(slice[s:], slice[s+1:]) // Shift everything after s down one
+	slice[len(slice)-1] = nil    // Erase last element (write zero value)
 	slice = slice[:len(slice)-1] // Truncate slice.
 
 	return slice
 }
+
+// removeProcess takes a process out of a queue if it's in there
+func removeProcess(slice []*Process, p *Process) []*Process {
+	for i, q := range slice {
+		if q == p {
+			return remove(slice, i)
+		}
+	}
+
+	return slice
+}
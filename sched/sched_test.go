@@ -1,7 +1,279 @@
 package sched
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
 
-func TestRemove(T *testing.T) {
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/config"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
 
+func newTestKernel() *Kernel {
+	return InitKernel(memory.InitMemory(32, 4096), make(chan *Process, 100), 8, time.Millisecond)
+}
+
+func newTestScheduler(policy SchedulingPolicy) *Scheduler {
+	return newTestKernel().AddCPU(cpu.InitCPU(0), policy)
+}
+
+func TestRemove(t *testing.T) {
+	procs := []*Process{{PID: 1}, {PID: 2}, {PID: 3}, {PID: 4}}
+
+	procs = remove(procs, 1)
+
+	expected := []int{1, 3, 4}
+	if len(procs) != len(expected) {
+		t.Fatalf("wrong length. want=%d, got=%d", len(expected), len(procs))
+	}
+
+	for i, pid := range expected {
+		if procs[i].PID != pid {
+			t.Errorf("wrong process at %d. want=%d, got=%d", i, pid, procs[i].PID)
+		}
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		ok        bool
+	}{
+		{"rr", true},
+		{"fcfs", true},
+		{"mlfq", false},
+		{"sjf", true},
+		{"srtf", true},
+		{"lottery", false},
+	}
+
+	for _, tt := range tests {
+		_, err := NewPolicy(&config.Sched{Algorithm: tt.algorithm, TimeQuantum: 5})
+		if (err == nil) != tt.ok {
+			t.Errorf("NewPolicy(%q) wrong error. want ok=%t, got=%v", tt.algorithm, tt.ok, err)
+		}
+	}
+}
+
+func TestRoundRobinQuantum(t *testing.T) {
+	s := newTestScheduler(NewRoundRobin(3))
+
+	p := CreateProcess("rr", 10, 32, code.Make(code.CALC, 10), 0, nil)
+
+	s.runProcess(p)
+
+	if p.State != READY {
+		t.Fatalf("process should be preempted. want=%d, got=%d", READY, p.State)
+	}
+
+	if s.CPU.TotalCycles != 3 {
+		t.Errorf("wrong number of cycles. want=3, got=%d", s.CPU.TotalCycles)
+	}
+
+	if len(s.ReadyQ) != 1 || s.ReadyQ[0] != p {
+		t.Errorf("preempted process should be back in the ready queue")
+	}
+}
+
+func TestFirstComeFirstServeRunsToCompletion(t *testing.T) {
+	s := newTestScheduler(NewFirstComeFirstServe())
+
+	p := CreateProcess("fcfs", 10, 32, code.Make(code.CALC, 10), 0, nil)
+
+	s.runProcess(p)
+
+	if p.State != EXIT {
+		t.Fatalf("process should have exited. want=%d, got=%d", EXIT, p.State)
+	}
+
+	if s.CPU.TotalCycles != 10 {
+		t.Errorf("wrong number of cycles. want=10, got=%d", s.CPU.TotalCycles)
+	}
+
+	if len(s.ReadyQ) != 0 {
+		t.Errorf("finished process shouldn't be in the ready queue")
+	}
+}
+
+func TestMultilevelFeedbackQueue(t *testing.T) {
+	mlfq, err := NewMultilevelFeedbackQueue(3, []int{2, 4, 8}, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := newTestScheduler(mlfq)
+
+	p := CreateProcess("mlfq", 20, 32, code.Make(code.CALC, 20), 0, nil)
+
+	// Burning the whole quantum demotes the process one level at a time
+	for _, want := range []int{1, 2, 2} {
+		s.runProcess(p)
+		s.ReadyQ = remove(s.ReadyQ, 0)
+
+		if p.priority != want {
+			t.Errorf("wrong priority after quantum expired. want=%d, got=%d", want, p.priority)
+		}
+	}
+
+	// Blocking promotes it
+	mlfq.OnBlock(p)
+	if p.priority != 1 {
+		t.Errorf("wrong priority after blocking. want=1, got=%d", p.priority)
+	}
+
+	// Higher levels run first and preempt lower levels
+	low := &Process{PID: 1, priority: 2}
+	high := &Process{PID: 2, priority: 0}
+	readyQ := []*Process{low, high}
+
+	if next := mlfq.Next(readyQ, s.CPU.TotalCycles); readyQ[next] != high {
+		t.Errorf("wrong process picked. want=%d, got=%d", high.PID, readyQ[next].PID)
+	}
+
+	if !mlfq.Preempt(low, readyQ) {
+		t.Errorf("higher level process should preempt lower level")
+	}
+
+	// Boost moves everyone back to the top
+	mlfq.Next(readyQ, s.CPU.TotalCycles+100)
+	if low.priority != 0 {
+		t.Errorf("process should have been boosted. want=0, got=%d", low.priority)
+	}
+}
+
+func TestShortestJobFirst(t *testing.T) {
+	sjf, err := NewShortestJobFirst(false, false, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	long := &Process{PID: 1, Runtime: 30}
+	short := &Process{PID: 2, Runtime: 5}
+	alsoShort := &Process{PID: 3, Runtime: 5}
+	readyQ := []*Process{long, short, alsoShort}
+
+	if next := sjf.Next(readyQ, 0); readyQ[next] != short {
+		t.Errorf("wrong process picked. want=%d, got=%d", short.PID, readyQ[next].PID)
+	}
+
+	if sjf.Preempt(long, readyQ) {
+		t.Errorf("sjf shouldn't preempt")
+	}
+
+	srtf, _ := NewShortestJobFirst(true, false, 0, 0)
+	if !srtf.Preempt(long, readyQ) {
+		t.Errorf("srtf should preempt when a shorter job is ready")
+	}
+}
+
+func TestShortestJobFirstRuntimeDecrements(t *testing.T) {
+	sjf, _ := NewShortestJobFirst(false, false, 0, 0)
+	s := newTestScheduler(sjf)
+
+	p := CreateProcess("sjf", 7, 32, append(code.Make(code.CALC, 4), code.Make(code.CALC, 3)...), 0, nil)
+
+	for i := 0; i < 4; i++ {
+		p.Execute(s)
+	}
+
+	if p.Runtime != 3 {
+		t.Errorf("wrong remaining runtime. want=3, got=%d", p.Runtime)
+	}
+}
+
+func TestBurstPredictor(t *testing.T) {
+	sjf, err := NewShortestJobFirst(false, true, 0.5, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := &Process{PID: 1}
+
+	if got := sjf.remaining(p); got != 10 {
+		t.Errorf("wrong initial estimate. want=10, got=%f", got)
+	}
+
+	// 0.5 * 4 + 0.5 * 10
+	p.burst = 4
+	sjf.OnBlock(p)
+
+	if p.estimate != 7 {
+		t.Errorf("wrong estimate. want=7, got=%f", p.estimate)
+	}
+
+	if _, err := NewShortestJobFirst(false, true, 2, 10); err == nil {
+		t.Errorf("alpha above 1 should be an error")
+	}
+}
+
+func TestContiguousAdmitsWhenHoleFits(t *testing.T) {
+	k := InitKernel(memory.InitMemory(32, 128), make(chan *Process, 100), 8, time.Millisecond)
+	k.Mem.AddContiguous("first", false)
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	big, small, late := newTestProcess(k), newTestProcess(k), newTestProcess(k)
+	big.Memory, small.Memory, late.Memory = 96, 20, 64
+
+	// Admitted by holes even though there are fewer free frames than the minimum
+	for _, p := range []*Process{big, small, late} {
+		s.admit(p)
+	}
+
+	if big.State != READY || small.State != READY || big.pages != 3 || small.pages != 1 {
+		t.Fatalf("first two processes should fit. big=%d small=%d", big.State, small.State)
+	}
+
+	if late.State != WAIT || late.pages != 0 {
+		t.Fatalf("no hole fits the last process yet")
+	}
+
+	// The hole big leaves behind fits it
+	s.Mem.RemovePages(big.PID)
+	s.assessWaiting()
+
+	if late.State != READY || late.pages != 2 {
+		t.Errorf("last process should be placed where big was. state=%d pages=%d", late.State, late.pages)
+	}
+}
+
+func TestLoadTemplateSegmentAddresses(t *testing.T) {
+	tests := []struct {
+		segmented bool
+		load      int
+		store     int
+	}{
+		{true, memory.SegmentAddress(memory.DataSegment, 8), memory.SegmentAddress(memory.HeapSegment, 16)},
+		{false, 32 + 8, 32 + 64 + 16}, // Right after the 32 bytes of code, then after the 64 of data
+	}
+
+	for _, tt := range tests {
+		ch := make(chan *Process, 1)
+		if err := LoadTemplate("../ProgramFiles/segments.prgm", 1, tt.segmented, ch); err != nil {
+			t.Fatalf("segmented=%t: template should load. got=%v", tt.segmented, err)
+		}
+
+		p := <-ch
+		load, store := int(code.ReadUint16(p.ins[1:])), int(code.ReadUint16(p.ins[6:]))
+		if load != tt.load || store != tt.store {
+			t.Errorf("segmented=%t: wrong addresses. want=%d,%d got=%d,%d", tt.segmented, tt.load, tt.store, load, store)
+		}
+	}
+
+	// Without segmentation an offset can't spill into the next segment
+	dir, err := ioutil.TempDir("", "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "past.prgm")
+	ioutil.WriteFile(filename, []byte("Name: PAST\nMemory: 64\nSegments: code:32 data:32\nLOAD data:40\n"), 0600)
+
+	if err := LoadTemplate(filename, 1, false, make(chan *Process, 1)); err == nil {
+		t.Errorf("address past the end of its segment should be rejected")
+	}
 }
@@ -0,0 +1,237 @@
+package sched
+
+import (
+	"fmt"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+)
+
+// Semaphore : counting semaphore for processes
+//
+// WAIT takes a unit or blocks while there are none left, SIGNAL gives a unit
+// back or hands it straight to the first process in line and wakes it past
+// its WAIT. Everything is guarded by the kernel lock.
+type Semaphore struct {
+	ID int // Key programs use to refer to the semaphore
+
+	count   int         // Units left
+	waiters []*Process  // Processes blocked on WAIT, first come first serve
+	holders map[int]int // Units taken by each PID and not signaled back yet
+	kernel  *Kernel
+}
+
+// InitSemaphore : create new semaphore with count units
+func InitSemaphore(k *Kernel, id int, count int) *Semaphore {
+	return &Semaphore{
+		ID:      id,
+		count:   count,
+		waiters: []*Process{},
+		holders: make(map[int]int),
+		kernel:  k,
+	}
+}
+
+// wait tries to take a unit for the process, false means there are none left
+func (sem *Semaphore) wait(p *Process) bool {
+	if sem.count == 0 {
+		return false
+	}
+
+	// Taking it could lead to a deadlock
+	if !sem.kernel.safe(p, sem.ID) {
+		return false
+	}
+
+	sem.count--
+	sem.holders[p.PID]++
+
+	return true
+}
+
+// signal gives a unit back and hands it to the first process in line that can have it
+func (sem *Semaphore) signal(p *Process) {
+
+	if sem.holders[p.PID] > 0 {
+		sem.holders[p.PID]--
+	}
+
+	sem.count++
+
+	// Anything given back can make a request kept waiting by avoidance safe
+	if sem.kernel.Avoidance {
+		sem.kernel.grant()
+		return
+	}
+
+	sem.grant()
+}
+
+// grant gives units left to processes in line that can have them and wakes them past their WAIT
+func (sem *Semaphore) grant() {
+	for i := 0; i < len(sem.waiters) && sem.count > 0; {
+		next := sem.waiters[i]
+
+		if !sem.wait(next) {
+			i++
+			continue
+		}
+
+		sem.waiters = remove(sem.waiters, i)
+		next.ip += 2
+
+		sem.kernel.wake(next)
+	}
+}
+
+// park waits in line for a unit
+func (sem *Semaphore) park(p *Process) {
+	sem.kernel.mu.Lock()
+	defer sem.kernel.mu.Unlock()
+
+	// Someone signaled in the meantime
+	if sem.wait(p) {
+		p.ip += 2
+		sem.kernel.wake(p)
+		return
+	}
+
+	sem.waiters = append(sem.waiters, p)
+}
+
+// Count : units left, the kernel lock must be held
+func (sem *Semaphore) Count() int {
+	return sem.count
+}
+
+// Cond : condition variable for processes
+//
+// CWAIT always blocks until a CSIGNAL, a process in the critical section
+// gives up the kernel lock while it waits and gets back in line for it when
+// signaled. Signals with nobody waiting are lost. Everything is guarded by
+// the kernel lock.
+type Cond struct {
+	ID int // Key programs use to refer to the condition variable
+
+	waiters []*Process // Processes blocked on CWAIT, first come first serve
+	kernel  *Kernel
+}
+
+// InitCond : create new condition variable
+func InitCond(k *Kernel, id int) *Cond {
+	return &Cond{
+		ID:      id,
+		waiters: []*Process{},
+		kernel:  k,
+	}
+}
+
+// wait queues the process right away, giving up the kernel lock if it has it,
+// so a signal between leaving the CPU and parking isn't lost
+func (c *Cond) wait(p *Process) {
+
+	if owner, locked := c.kernel.Lock.Owner(); locked && owner == p.PID {
+		c.kernel.Lock.release(p)
+		p.relock = true
+	}
+
+	c.waiters = append(c.waiters, p)
+}
+
+// signal wakes the first process in line
+func (c *Cond) signal() {
+	if len(c.waiters) == 0 {
+		return
+	}
+
+	next := c.waiters[0]
+	c.waiters = remove(c.waiters, 0)
+
+	// Still on its way off the CPU, park will resume it
+	if !next.parked {
+		next.signaled = true
+		return
+	}
+
+	c.resume(next)
+}
+
+// resume moves a signaled process past its CWAIT, back through the kernel lock if it gave it up
+func (c *Cond) resume(p *Process) {
+	p.parked = false
+	p.signaled = false
+
+	if p.relock {
+		p.relock = false
+
+		if !c.kernel.Lock.acquire(p) {
+			p.waitingOn = c.kernel.Lock
+			c.kernel.Lock.waiters = append(c.kernel.Lock.waiters, p)
+			return
+		}
+	}
+
+	p.ip += 2
+	c.kernel.wake(p)
+}
+
+// park waits for a signal, the process is already in line from CWAIT
+func (c *Cond) park(p *Process) {
+	c.kernel.mu.Lock()
+	defer c.kernel.mu.Unlock()
+
+	p.parked = true
+
+	// Signaled in the meantime
+	if p.signaled {
+		c.resume(p)
+	}
+}
+
+// String : string representation of semaphore
+func (sem *Semaphore) String() string {
+	return fmt.Sprintf("semaphore %d", sem.ID)
+}
+
+// waiting : processes in line for a unit
+func (sem *Semaphore) waiting() []*Process {
+	return sem.waiters
+}
+
+// wakes : anyone with a SIGNAL left for the semaphore
+func (sem *Semaphore) wakes(p *Process, q *Process, from int) bool {
+	return q.mayDo(code.SIGNAL, sem.ID, from)
+}
+
+// cancel takes a process out of line for a unit
+func (sem *Semaphore) cancel(p *Process) {
+	sem.waiters = removeProcess(sem.waiters, p)
+}
+
+// String : string representation of condition variable
+func (c *Cond) String() string {
+	return fmt.Sprintf("condition %d", c.ID)
+}
+
+// waiting : processes parked waiting for a signal, ones still on their way off the CPU aren't counted yet
+func (c *Cond) waiting() []*Process {
+	parked := []*Process{}
+	for _, p := range c.waiters {
+		if p.parked {
+			parked = append(parked, p)
+		}
+	}
+
+	return parked
+}
+
+// wakes : anyone with a CSIGNAL left for the condition variable
+func (c *Cond) wakes(p *Process, q *Process, from int) bool {
+	return q.mayDo(code.CSIGNAL, c.ID, from)
+}
+
+// cancel takes a process out of line for a signal
+func (c *Cond) cancel(p *Process) {
+	c.waiters = removeProcess(c.waiters, p)
+
+	p.relock, p.signaled, p.parked = false, false, false
+}
@@ -0,0 +1,111 @@
+package sched
+
+import "fmt"
+
+// ShortestJobFirst : the process with the least CALC work left runs first
+//
+// Without a predictor it is an "oracle" that knows the exact remaining work from
+// Process.Runtime. With a predictor the length of the next CPU burst is guessed with
+// exponential averaging of the previous bursts:
+//
+//	estimate = Alpha * lastBurst + (1 - Alpha) * estimate
+//
+// The preemptive version is shortest remaining time first (SRTF).
+type ShortestJobFirst struct {
+	Preemptive      bool    // Preempt the running process when a shorter one is ready (SRTF)
+	Predict         bool    // Use the burst predictor instead of the real remaining work
+	Alpha           float64 // Weight of the most recent burst in the prediction
+	InitialEstimate float64 // Prediction for processes that haven't had a burst yet
+}
+
+// NewShortestJobFirst : create sjf policy, or srtf if preemptive
+func NewShortestJobFirst(preemptive bool, predict bool, alpha float64, initialEstimate float64) (*ShortestJobFirst, error) {
+
+	if predict {
+		if alpha < 0 || alpha > 1 {
+			return nil, fmt.Errorf("burst predictor alpha must be between 0 and 1")
+		}
+
+		if initialEstimate <= 0 {
+			return nil, fmt.Errorf("burst predictor initial estimate must be above zero")
+		}
+	}
+
+	return &ShortestJobFirst{
+		Preemptive:      preemptive,
+		Predict:         predict,
+		Alpha:           alpha,
+		InitialEstimate: initialEstimate,
+	}, nil
+}
+
+// Next : shortest job in the ready queue, ties go to whoever has been waiting longest
+func (sjf *ShortestJobFirst) Next(readyQ []*Process, now int) int {
+	next := 0
+	for i, p := range readyQ {
+		if sjf.remaining(p) < sjf.remaining(readyQ[next]) {
+			next = i
+		}
+	}
+
+	return next
+}
+
+// Quantum : no time slicing
+func (sjf *ShortestJobFirst) Quantum(p *Process) int {
+	return 0
+}
+
+// Preempt : only srtf preempts, when a process with less remaining work is ready
+func (sjf *ShortestJobFirst) Preempt(cur *Process, readyQ []*Process) bool {
+	if !sjf.Preemptive {
+		return false
+	}
+
+	for _, p := range readyQ {
+		if sjf.remaining(p) < sjf.remaining(cur) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OnQuantumExpire : never happens since there's no quantum
+func (sjf *ShortestJobFirst) OnQuantumExpire(p *Process) {}
+
+// OnBlock : the CPU burst is over so feed it to the predictor
+func (sjf *ShortestJobFirst) OnBlock(p *Process) {
+	if !sjf.Predict {
+		return
+	}
+
+	p.estimate = sjf.Alpha*float64(p.burst) + (1-sjf.Alpha)*sjf.estimate(p)
+}
+
+// OnExit : nothing to do
+func (sjf *ShortestJobFirst) OnExit(p *Process) {}
+
+// remaining work for a process, either known or predicted
+func (sjf *ShortestJobFirst) remaining(p *Process) float64 {
+	if !sjf.Predict {
+		return float64(p.Runtime)
+	}
+
+	// Take out what has already run of the current burst
+	left := sjf.estimate(p) - float64(p.burst)
+	if left < 0 {
+		return 0
+	}
+
+	return left
+}
+
+// estimate of the next burst, using the initial estimate until there's history
+func (sjf *ShortestJobFirst) estimate(p *Process) float64 {
+	if p.estimate <= 0 {
+		return sjf.InitialEstimate
+	}
+
+	return p.estimate
+}
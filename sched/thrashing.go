@@ -0,0 +1,224 @@
+package sched
+
+import (
+	"sync"
+	"time"
+)
+
+// PFF : page fault frequency controller that keeps the system from thrashing
+//
+// Every interval it looks at the page faults per memory reference since the
+// last check and the working sets of the processes sharing memory. When their
+// working sets don't fit in physical memory or faults go above Upper, the ready
+// process faulting the most is suspended and its pages swapped out. Once faults
+// go below Lower and the working set it had fits again, the process that was
+// suspended first goes back in a ready queue, or right away once nothing is left
+// in memory.
+type PFF struct {
+	Interval time.Duration // Time between checks
+	Lower    float64       // Fault rate below which a suspended process can come back
+	Upper    float64       // Fault rate above which a process is suspended
+
+	suspended []*Process     // Suspended processes, the first is resumed first
+	sizes     map[int]int    // Working set of each suspended process when it was suspended
+	last      map[int][2]int // References and faults of each process at the last check
+	rates     []float64      // Sum of the fault rates seen with each degree of multiprogramming, index 0 is one process
+	samples   []int          // Number of fault rates summed up for each degree of multiprogramming
+	kernel    *Kernel
+	mu        sync.Mutex // Guards everything the TUI reads
+}
+
+// InitPFF : create new page fault frequency controller
+func InitPFF(k *Kernel, interval time.Duration, lower float64, upper float64) *PFF {
+	return &PFF{
+		Interval:  interval,
+		Lower:     lower,
+		Upper:     upper,
+		suspended: []*Process{},
+		sizes:     make(map[int]int),
+		last:      make(map[int][2]int),
+		rates:     []float64{},
+		samples:   []int{},
+		kernel:    k,
+	}
+}
+
+// Run : check for thrashing until the kernel stops
+func (c *PFF) Run() {
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.check()
+		case <-c.kernel.quit:
+			return
+		}
+	}
+}
+
+// Suspended : number of suspended processes
+func (c *PFF) Suspended() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.suspended)
+}
+
+// Curve : average fault rate seen with each degree of multiprogramming, index 0 is one process
+func (c *PFF) Curve() []float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	curve := make([]float64, len(c.rates))
+	for i := range c.rates {
+		if c.samples[i] > 0 {
+			curve[i] = c.rates[i] / float64(c.samples[i])
+		}
+	}
+
+	return curve
+}
+
+// check measures the fault rate and suspends or resumes a process
+func (c *PFF) check() {
+	k := c.kernel
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Killed processes go back so they can exit
+	for i := 0; i < len(c.suspended); i++ {
+		if c.suspended[i].isKilled() {
+			c.resume(i)
+			i--
+		}
+	}
+
+	isSuspended := make(map[int]bool, len(c.suspended))
+	for _, p := range c.suspended {
+		isSuspended[p.PID] = true
+	}
+
+	// Fault rate of each process in memory since the last check
+	rates := make(map[int]float64)
+	last := make(map[int][2]int)
+	refs, faults, workingSet, degree := 0, 0, 0, 0
+
+	for pid, p := range k.procs {
+		if p.zombie || isSuspended[pid] {
+			continue
+		}
+
+		degree++
+
+		r, f := k.Mem.ProcessStats(pid)
+		last[pid] = [2]int{r, f}
+
+		dr, df := r-c.last[pid][0], f-c.last[pid][1]
+		if dr > 0 {
+			rates[pid] = float64(df) / float64(dr)
+		}
+
+		refs += dr
+		faults += df
+		workingSet += k.Mem.WorkingSet(pid)
+	}
+
+	// Suspended processes pick up where they left off when they come back
+	for _, p := range c.suspended {
+		last[p.PID] = c.last[p.PID]
+	}
+
+	c.last = last
+
+	// Nothing left in memory to make room for
+	if degree == 0 {
+		if len(c.suspended) > 0 {
+			c.resume(0)
+		}
+		return
+	}
+
+	// Everything in memory finished or blocked, nothing is faulting
+	if refs == 0 {
+		if len(c.suspended) > 0 && workingSet+c.sizes[c.suspended[0].PID] <= k.Mem.Frames() {
+			c.resume(0)
+		}
+		return
+	}
+
+	rate := float64(faults) / float64(refs)
+
+	for len(c.rates) < degree {
+		c.rates = append(c.rates, 0)
+		c.samples = append(c.samples, 0)
+	}
+
+	c.rates[degree-1] += rate
+	c.samples[degree-1]++
+
+	if (workingSet > k.Mem.Frames() || rate > c.Upper) && degree > 1 {
+		c.suspend(rates)
+		return
+	}
+
+	if len(c.suspended) > 0 && rate < c.Lower && workingSet+c.sizes[c.suspended[0].PID] <= k.Mem.Frames() {
+		c.resume(0)
+	}
+}
+
+// suspend takes the ready process faulting the most off the CPUs and swaps it out, the kernel lock must be held
+func (c *PFF) suspend(rates map[int]float64) {
+	k := c.kernel
+
+	var victim *Process
+	var from *Scheduler
+
+	for _, s := range k.Schedulers {
+		s.mu.Lock()
+		for _, p := range s.ReadyQ {
+			if victim == nil || rates[p.PID] > rates[victim.PID] {
+				victim, from = p, s
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	if victim == nil {
+		return
+	}
+
+	// It may have gotten the CPU in the meantime, try again next time
+	from.mu.Lock()
+	n := len(from.ReadyQ)
+	from.ReadyQ = removeProcess(from.ReadyQ, victim)
+	if len(from.ReadyQ) == n {
+		from.mu.Unlock()
+		return
+	}
+	victim.State = SUSPENDED
+	from.mu.Unlock()
+
+	c.sizes[victim.PID] = k.Mem.WorkingSet(victim.PID)
+	c.suspended = append(c.suspended, victim)
+
+	out := k.Mem.SwapOut(victim.PID)
+	k.logEvent("process %d: suspended with %d pages swapped out", victim.PID, out)
+}
+
+// resume puts the ith suspended process back in a ready queue, the kernel lock must be held
+func (c *PFF) resume(i int) {
+	p := c.suspended[i]
+
+	c.suspended = append(c.suspended[:i], c.suspended[i+1:]...)
+	delete(c.sizes, p.PID)
+
+	c.kernel.logEvent("process %d: resumed", p.PID)
+	c.kernel.leastLoaded().ready(p)
+}
@@ -0,0 +1,101 @@
+package sched
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
+
+// touch reads a virtual page of a process in an instruction of its own, paging it in if it faults
+func touch(k *Kernel, p *Process, vpn int) {
+	k.Mem.Tick()
+
+	if _, err := k.Mem.Translate(p.PID, vpn*k.Mem.PageSize, memory.ProtRead); err == memory.ErrPageFault {
+		k.Mem.PageIn(p.PID, vpn)
+	}
+}
+
+func TestPFFSuspendsAndResumes(t *testing.T) {
+	k := InitKernel(memory.InitMemory(32, 256), make(chan *Process, 100), 8, time.Millisecond)
+	k.Mem.Window = 2
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	c := InitPFF(k, time.Second, 0.1, 0.5)
+
+	faulty, quiet := newTestProcess(k), newTestProcess(k)
+	for _, p := range []*Process{faulty, quiet} {
+		p.pages = k.Mem.Add(128, p.PID)
+		s.ready(p)
+	}
+
+	// Every reference of one faults, one in four of the other
+	for vpn := 0; vpn < 4; vpn++ {
+		touch(k, faulty, vpn)
+		touch(k, quiet, 0)
+	}
+
+	c.check()
+
+	if faulty.State != SUSPENDED || len(s.ReadyQ) != 1 || s.ReadyQ[0] != quiet {
+		t.Fatalf("process faulting the most should be suspended. state=%d ready=%d", faulty.State, len(s.ReadyQ))
+	}
+
+	for vpn, e := range k.Mem.PageTable(faulty.PID) {
+		if e.Valid {
+			t.Errorf("page %d of the suspended process should be swapped out", vpn)
+		}
+	}
+
+	// Faults stop once the other has memory to itself
+	for i := 0; i < 20; i++ {
+		touch(k, quiet, 0)
+	}
+
+	c.check()
+
+	if faulty.State != READY || len(s.ReadyQ) != 2 || c.Suspended() != 0 {
+		t.Errorf("suspended process should be back. state=%d ready=%d", faulty.State, len(s.ReadyQ))
+	}
+
+	if curve := c.Curve(); len(curve) != 2 || curve[0] != 0 || curve[1] != 5.0/8 {
+		t.Errorf("wrong fault rate curve. got=%v", curve)
+	}
+}
+
+func TestPFFResumesOnceMemoryDrains(t *testing.T) {
+	k := InitKernel(memory.InitMemory(32, 256), make(chan *Process, 100), 8, time.Millisecond)
+	k.Mem.Window = 2
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	c := InitPFF(k, time.Second, 0.1, 0.5)
+
+	faulty, quiet := newTestProcess(k), newTestProcess(k)
+	for _, p := range []*Process{faulty, quiet} {
+		p.pages = k.Mem.Add(128, p.PID)
+		s.ready(p)
+	}
+
+	for vpn := 0; vpn < 4; vpn++ {
+		touch(k, faulty, vpn)
+		touch(k, quiet, 0)
+	}
+
+	c.check()
+
+	if faulty.State != SUSPENDED {
+		t.Fatalf("process faulting the most should be suspended. state=%d", faulty.State)
+	}
+
+	// The other process exits without touching memory again
+	s.mu.Lock()
+	s.ReadyQ = removeProcess(s.ReadyQ, quiet)
+	s.mu.Unlock()
+	k.Mem.RemovePages(quiet.PID)
+	k.forget(quiet)
+
+	c.check()
+
+	if faulty.State != READY || c.Suspended() != 0 {
+		t.Errorf("suspended process should come back once nothing is left in memory. state=%d", faulty.State)
+	}
+}
@@ -0,0 +1,197 @@
+package sched
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	// StatusKilled : exit status of a process terminated by the kernel
+	StatusKilled = 255
+)
+
+// Children : what a parent blocks on with WAIT until one of its children exits
+//
+// A child that exits stays in the process table as a ZOMBIE holding its exit
+// status until its parent reaps it. Children of init, including orphans whose
+// parent exited first, are reaped right away. Everything is guarded by the
+// kernel lock.
+type Children struct {
+	waiters []*Process // Parents blocked on WAIT
+	kernel  *Kernel
+}
+
+// InitChildren : create new place for parents to wait on their children
+func InitChildren(k *Kernel) *Children {
+	return &Children{
+		waiters: []*Process{},
+		kernel:  k,
+	}
+}
+
+// park waits for a child to exit
+func (c *Children) park(p *Process) {
+	c.kernel.mu.Lock()
+	defer c.kernel.mu.Unlock()
+
+	// A child exited in the meantime
+	if status, ok := c.kernel.reap(p); ok || len(p.children) == 0 {
+		p.acc = byte(status)
+		p.ip++
+		c.kernel.wake(p)
+		return
+	}
+
+	c.waiters = append(c.waiters, p)
+}
+
+// String : string representation of children
+func (c *Children) String() string {
+	return "child exit"
+}
+
+// waiting : parents blocked on WAIT
+func (c *Children) waiting() []*Process {
+	return c.waiters
+}
+
+// wakes : any child can wake its parent by exiting
+func (c *Children) wakes(p *Process, q *Process, from int) bool {
+	return q.parent == p
+}
+
+// cancel takes a parent out of line
+func (c *Children) cancel(p *Process) {
+	c.waiters = removeProcess(c.waiters, p)
+}
+
+// ProcessNode : process in the process tree
+type ProcessNode struct {
+	PID      int
+	Name     string
+	Zombie   bool
+	Children []*ProcessNode
+}
+
+// ProcessTree : every process in the process table under init
+func (k *Kernel) ProcessTree() *ProcessNode {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	nodes := map[*Process]*ProcessNode{
+		k.init: {PID: k.init.PID, Name: k.init.Name},
+	}
+
+	procs := make([]*Process, 0, len(k.procs))
+	for _, p := range k.procs {
+		procs = append(procs, p)
+		nodes[p] = &ProcessNode{PID: p.PID, Name: p.Name, Zombie: p.zombie}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+
+	for _, p := range procs {
+		parent, ok := nodes[p.parent]
+		if !ok {
+			parent = nodes[k.init]
+		}
+
+		parent.Children = append(parent.Children, nodes[p])
+	}
+
+	return nodes[k.init]
+}
+
+// reap takes the exit status of a ZOMBIE child and removes it from the process table, the kernel lock must be held
+func (k *Kernel) reap(p *Process) (int, bool) {
+	for i, child := range p.children {
+		if !child.zombie {
+			continue
+		}
+
+		p.children = remove(p.children, i)
+		k.forget(child)
+
+		return child.status, true
+	}
+
+	return 0, false
+}
+
+// orphan hands the children of an exiting process to init, the kernel lock must be held
+func (k *Kernel) orphan(p *Process) {
+	for _, child := range p.children {
+		child.parent = k.init
+
+		// Init reaps its children right away
+		if child.zombie {
+			k.forget(child)
+			continue
+		}
+
+		if k.Cascade {
+			k.kill(child)
+		}
+	}
+
+	p.children = nil
+}
+
+// bury turns an exited process into a ZOMBIE for its parent to reap, the kernel lock must be held
+func (k *Kernel) bury(p *Process) {
+
+	// Init reaps its children right away
+	if p.parent == nil || p.parent == k.init {
+		k.forget(p)
+		return
+	}
+
+	p.zombie = true
+	p.State = ZOMBIE
+
+	// Parent is already waiting on it
+	parent := p.parent
+	for _, waiter := range k.Children.waiters {
+		if waiter != parent {
+			continue
+		}
+
+		status, _ := k.reap(parent)
+		k.Children.cancel(parent)
+
+		parent.acc = byte(status)
+		parent.ip++
+		k.wake(parent)
+		return
+	}
+}
+
+// forget takes a process out of the process table for good and frees its process control block, the kernel lock must be held
+func (k *Kernel) forget(p *Process) {
+	delete(k.procs, p.PID)
+
+	if p.pcb >= 0 && k.ProcessCache != nil {
+		k.ProcessCache.Free(p.pcb)
+		p.pcb = -1
+	}
+}
+
+// kill terminates a process the next time it's on a CPU, the kernel lock must be held
+func (k *Kernel) kill(p *Process) {
+	atomic.StoreInt32(&p.killed, 1)
+
+	// Get it off whatever it's blocked on so it can exit
+	for _, w := range k.waitables() {
+		for _, waiting := range w.waiting() {
+			if waiting == p {
+				w.cancel(p)
+				k.wake(p)
+				return
+			}
+		}
+	}
+}
+
+// isKilled checks if the kernel terminated the process
+func (p *Process) isKilled() bool {
+	return atomic.LoadInt32(&p.killed) == 1
+}
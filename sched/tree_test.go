@@ -0,0 +1,162 @@
+package sched
+
+import (
+	"testing"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/code"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/cpu"
+)
+
+// newTestChild registers a child of the process running the program like FORK would
+func newTestChild(k *Kernel, parent *Process, program ...[]byte) *Process {
+	ins := code.Instructions{}
+	for _, instruction := range program {
+		ins = append(ins, instruction...)
+	}
+
+	child := CreateProcess("child", 0, 32, ins, 0, parent)
+	parent.children = append(parent.children, child)
+	k.register(child)
+
+	return child
+}
+
+func TestWaitReapsZombieChild(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	parent := newTestProcess(k, code.Make(code.WAITCHILD))
+	child := newTestChild(k, parent, code.Make(code.HALT, 7))
+
+	if err := child.Execute(s); err == nil {
+		t.Fatalf("HALT should end the process")
+	}
+	s.exit(child)
+
+	if !child.zombie || child.State != ZOMBIE || k.Live() != 2 {
+		t.Fatalf("child should stay a zombie until it's reaped")
+	}
+
+	if err := parent.Execute(s); err != nil {
+		t.Fatalf("WAIT with a zombie child shouldn't block. got=%v", err)
+	}
+
+	if parent.acc != 7 || len(parent.children) != 0 || k.Live() != 1 {
+		t.Errorf("parent should reap the child and get its status. got=%d", parent.acc)
+	}
+}
+
+func TestWaitBlocksUntilChildExits(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	parent := newTestProcess(k, code.Make(code.WAITCHILD))
+	child := newTestChild(k, parent, code.Make(code.HALT, 3))
+
+	block(t, s, parent)
+
+	if parent.waitingOn != k.Children {
+		t.Fatalf("parent should wait on its children")
+	}
+
+	child.Execute(s)
+	s.exit(child)
+
+	if parent.State != READY || parent.ip != 1 || parent.acc != 3 {
+		t.Errorf("parent should be woken with the child's status. got=%d", parent.acc)
+	}
+
+	if k.Live() != 1 {
+		t.Errorf("child should be reaped. got=%d processes", k.Live())
+	}
+}
+
+func TestOrphansGoToInit(t *testing.T) {
+	for _, cascade := range []bool{false, true} {
+		k := newTestKernel()
+		s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+		k.Cascade = cascade
+
+		parent := newTestProcess(k, code.Make(code.CALC, 1))
+		child := newTestChild(k, parent, code.Make(code.CALC, 1))
+		grandchild := newTestChild(k, child, code.Make(code.RECV))
+
+		tree := k.ProcessTree()
+		if len(tree.Children) != 1 || tree.Children[0].PID != parent.PID || tree.Children[0].Children[0].Children[0].PID != grandchild.PID {
+			t.Fatalf("process tree should go init, parent, child, grandchild")
+		}
+
+		s.exit(parent)
+
+		if child.parent != k.init {
+			t.Errorf("orphan should belong to init")
+		}
+
+		if child.isKilled() != cascade {
+			t.Errorf("child killed=%t with cascade=%t", child.isKilled(), cascade)
+		}
+
+		// Cascades all the way down
+		s.exit(child)
+
+		if grandchild.parent != k.init || grandchild.isKilled() != cascade {
+			t.Errorf("grandchild should belong to init and be killed=%t", cascade)
+		}
+	}
+}
+
+func TestInitReapsZombiesOfExitingParent(t *testing.T) {
+	k := newTestKernel()
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+
+	parent := newTestProcess(k, code.Make(code.CALC, 1))
+	child := newTestChild(k, parent, code.Make(code.CALC, 1))
+
+	s.exit(child)
+	s.exit(parent)
+
+	if k.Live() != 0 {
+		t.Errorf("init should reap the zombie. got=%d processes", k.Live())
+	}
+}
+
+func TestZombieKeepsProcessControlBlock(t *testing.T) {
+	k := newTestKernel()
+	k.Mem.AddBuddy(0)
+	k.Mem.AddCache(SchedulerCache, SchedulerSize)
+
+	pcbs, _ := k.Mem.AddCache("pcb", PCBSize)
+	k.ProcessCache = pcbs
+
+	s := k.AddCPU(cpu.InitCPU(0), NewRoundRobin(5))
+	if s.block < 0 {
+		t.Errorf("scheduler should get kernel memory for its bookkeeping")
+	}
+
+	parent := newTestProcess(k, code.Make(code.WAITCHILD))
+	child := newTestChild(k, parent, code.Make(code.HALT, 0))
+
+	if used, _, _ := pcbs.Stats(); used != 2 || parent.pcb == child.pcb {
+		t.Fatalf("each process should get its own control block once it's registered. used=%d", used)
+	}
+
+	s.admit(parent)
+	s.admit(child)
+
+	if used, _, _ := pcbs.Stats(); used != 2 {
+		t.Fatalf("admitting shouldn't take more control blocks. used=%d", used)
+	}
+
+	child.Execute(s)
+	s.exit(child)
+
+	if used, _, _ := pcbs.Stats(); used != 2 {
+		t.Errorf("zombie should keep its control block until it's reaped. used=%d", used)
+	}
+
+	parent.Execute(s)
+
+	if used, _, _ := pcbs.Stats(); used != 1 || child.pcb != -1 {
+		t.Errorf("reaping should free the control block. used=%d", used)
+	}
+}
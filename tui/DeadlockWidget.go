@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/sched"
+)
+
+type DeadlockWidget struct {
+	*widgets.List
+	updateInterval time.Duration
+	detector       *sched.Detector
+}
+
+func NewDeadlockWidget(d *sched.Detector) *DeadlockWidget {
+	dw := &DeadlockWidget{
+		List:           widgets.NewList(),
+		updateInterval: time.Second,
+		detector:       d,
+	}
+	dw.Title = " Deadlocks "
+	dw.WrapText = false
+
+	dw.update()
+
+	go func() {
+		for range time.NewTicker(dw.updateInterval).C {
+			dw.Lock()
+			dw.update()
+			dw.Unlock()
+		}
+	}()
+
+	return dw
+}
+
+// update : one row per cycle found by the last check
+func (dw *DeadlockWidget) update() {
+	if dw.detector == nil {
+		dw.Rows = []string{"detection is off"}
+		return
+	}
+
+	deadlocks := dw.detector.Deadlocks()
+
+	rows := make([]string, len(deadlocks))
+	for i, deadlock := range deadlocks {
+		rows[i] = deadlock.String()
+	}
+
+	dw.Rows = rows
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/sched"
+)
+
+type EventWidget struct {
+	*widgets.List
+	updateInterval time.Duration
+	kernel         *sched.Kernel
+}
+
+func NewEventWidget(k *sched.Kernel) *EventWidget {
+	e := &EventWidget{
+		List:           widgets.NewList(),
+		updateInterval: time.Second,
+		kernel:         k,
+	}
+	e.Title = " Kernel Events "
+	e.WrapText = false
+
+	e.update()
+
+	go func() {
+		for range time.NewTicker(e.updateInterval).C {
+			e.Lock()
+			e.update()
+			e.Unlock()
+		}
+	}()
+
+	return e
+}
+
+// update : newest events at the top
+func (e *EventWidget) update() {
+	events := e.kernel.Events()
+
+	rows := make([]string, len(events))
+	for i, event := range events {
+		rows[len(events)-1-i] = event
+	}
+
+	e.Rows = rows
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
+
+// KernelMemWidget : buddy allocator free lists and slab cache usage
+type KernelMemWidget struct {
+	*widgets.List
+	updateInterval time.Duration
+	memory         *memory.Memory
+}
+
+func NewKernelMemWidget(mem *memory.Memory) *KernelMemWidget {
+	k := &KernelMemWidget{
+		List:           widgets.NewList(),
+		updateInterval: time.Second,
+		memory:         mem,
+	}
+	k.Title = " Kernel Memory "
+	k.WrapText = false
+
+	k.update()
+
+	go func() {
+		for range time.NewTicker(k.updateInterval).C {
+			k.Lock()
+			k.update()
+			k.Unlock()
+		}
+	}()
+
+	return k
+}
+
+// update : used objects of each cache then free blocks of each order
+func (k *KernelMemWidget) update() {
+	buddy := k.memory.Buddy
+	if buddy == nil {
+		k.Rows = []string{"kernel memory is off"}
+		return
+	}
+
+	frames, evictions := buddy.Stats()
+	k.Title = fmt.Sprintf(" Kernel Memory (%d frames, %d evictions) ", frames, evictions)
+
+	rows := []string{}
+	for _, c := range k.memory.Caches() {
+		used, capacity, failures := c.Stats()
+
+		usage := 0.0
+		if capacity > 0 {
+			usage = 100 * float64(used) / float64(capacity)
+		}
+
+		rows = append(rows, fmt.Sprintf("%s: %d/%d objects (%.0f%%), %d failed", c.Name, used, capacity, usage, failures))
+	}
+
+	for order, blocks := range buddy.FreeLists() {
+		rows = append(rows, fmt.Sprintf("order %d (%d frames): %d free", order, 1<<order, blocks))
+	}
+
+	k.Rows = rows
+}
@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/sched"
+)
+
+type MailWidget struct {
+	*widgets.BarChart
+	updateInterval time.Duration
+	kernel         *sched.Kernel
+}
+
+func NewMailWidget(k *sched.Kernel) *MailWidget {
+	m := &MailWidget{
+		BarChart:       widgets.NewBarChart(),
+		updateInterval: time.Second,
+		kernel:         k,
+	}
+	m.Title = " Mailbox Depth "
+	m.BarWidth = 3
+
+	m.Labels = make([]string, len(k.Mailboxes))
+	for i := range k.Mailboxes {
+		m.Labels[i] = strconv.Itoa(i)
+	}
+
+	m.update()
+
+	go func() {
+		for range time.NewTicker(m.updateInterval).C {
+			m.Lock()
+			m.update()
+			m.Unlock()
+		}
+	}()
+
+	return m
+}
+
+// update : number of messages in each mailbox
+func (m *MailWidget) update() {
+	depths := m.kernel.MailboxDepths()
+
+	m.Data = make([]float64, len(depths))
+	for i, depth := range depths {
+		m.Data[i] = float64(depth)
+	}
+}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/gizak/termui/v3/widgets"
@@ -39,7 +40,9 @@ func (m *MemWidget) updateMainMemory() {
 	// 	Used:        mainMemory.Used,
 	// 	UsedPercent: mainMemory.UsedPercent,
 	// })
-	m.Data[0] = append([]float64{float64(len(m.memory.PhysicalMemory))}, m.Data[0]...)
+	physical, _ := m.memory.Usage()
+
+	m.Data[0] = append([]float64{float64(physical)}, m.Data[0]...)
 }
 
 func (m *MemWidget) updateVirtualMemory() {
@@ -49,8 +52,23 @@ func (m *MemWidget) updateVirtualMemory() {
 	// 	UsedPercent: mainMemory.UsedPercent,
 	// })
 
-	m.Data[1] = append([]float64{float64(len(m.memory.VirtualMemory))}, m.Data[1]...)
+	_, virtual := m.memory.Usage()
+
+	m.Data[1] = append([]float64{float64(virtual)}, m.Data[1]...)
+
+}
+
+// updateTitle : show how many page faults, copy-on-write faults, swaps and corrupted pages there have been,
+// or how fragmented memory is with contiguous allocation
+func (m *MemWidget) updateTitle() {
+	if m.memory.Contiguous != nil {
+		passes, moved := m.memory.Compactions()
+		m.Title = fmt.Sprintf(" Memory Usage (%d holes, %.0f%% fragmented, %d compactions moved %d) ", len(m.memory.Holes()), 100*m.memory.Fragmentation(), passes, moved)
+		return
+	}
 
+	ins, outs := m.memory.SwapStats()
+	m.Title = fmt.Sprintf(" Memory Usage (%d page faults, %d COW faults, %d in/%d out of swap, %d corrupt) ", m.memory.Faults(), m.memory.COWFaults(), ins, outs, len(m.memory.Corrupted()))
 }
 
 func NewMemWidget(mem *memory.Memory) *MemWidget {
@@ -69,12 +87,14 @@ func NewMemWidget(mem *memory.Memory) *MemWidget {
 
 	m.updateMainMemory()
 	m.updateVirtualMemory()
+	m.updateTitle()
 
 	go func() {
 		for range time.NewTicker(m.updateInterval).C {
 			m.Lock()
 			m.updateMainMemory()
 			m.updateVirtualMemory()
+			m.updateTitle()
 			m.Unlock()
 		}
 	}()
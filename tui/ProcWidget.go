@@ -14,10 +14,10 @@ import (
 type ProcWidget struct {
 	*widgets.Table
 	updateInterval time.Duration
-	processes      *[]*sched.Process
+	processes      func() []sched.ProcessInfo
 }
 
-func NewProcWidget(processes *[]*sched.Process) *ProcWidget {
+func NewProcWidget(processes func() []sched.ProcessInfo) *ProcWidget {
 	self := &ProcWidget{
 		Table:          widgets.NewTable(),
 		updateInterval: time.Second,
@@ -40,16 +40,19 @@ func NewProcWidget(processes *[]*sched.Process) *ProcWidget {
 	return self
 }
 
-// update :  converts a []*kernel.Process to a [][]string and sets it to the table Rows
+// update :  converts a []sched.ProcessInfo to a [][]string and sets it to the table Rows
 func (p *ProcWidget) update() {
-	strings := make([][]string, len(*p.processes)+1)
-	strings[0] = []string{"PID", "Name", "CPU", "Mem"}
-	for i := range *p.processes {
-		strings[i+1] = make([]string, 4)
-		strings[i+1][0] = strconv.Itoa((*p.processes)[i].PID)
-		strings[i+1][1] = (*p.processes)[i].Name
-		strings[i+1][2] = fmt.Sprintf("%4s", strconv.Itoa((*p.processes)[i].Runtime))
-		strings[i+1][3] = fmt.Sprintf("%4s", strconv.Itoa((*p.processes)[i].Memory))
+	processes := p.processes()
+
+	strings := make([][]string, len(processes)+1)
+	strings[0] = []string{"PID", "Name", "CPU", "Mem", "Faults"}
+	for i := range processes {
+		strings[i+1] = make([]string, 5)
+		strings[i+1][0] = strconv.Itoa(processes[i].PID)
+		strings[i+1][1] = processes[i].Name
+		strings[i+1][2] = fmt.Sprintf("%4s", strconv.Itoa(processes[i].Runtime))
+		strings[i+1][3] = fmt.Sprintf("%4s", strconv.Itoa(processes[i].Memory))
+		strings[i+1][4] = fmt.Sprintf("%4s", strconv.Itoa(processes[i].Faults))
 	}
 
 	p.Rows = strings
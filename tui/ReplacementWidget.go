@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
+
+// ReplacementWidget : page faults every replacement policy would have had on the pages touched recently
+type ReplacementWidget struct {
+	*widgets.BarChart
+	updateInterval time.Duration
+	memory         *memory.Memory
+}
+
+func NewReplacementWidget(mem *memory.Memory) *ReplacementWidget {
+	r := &ReplacementWidget{
+		BarChart:       widgets.NewBarChart(),
+		updateInterval: time.Second,
+		memory:         mem,
+	}
+	r.Title = " Faults by Replacement Policy "
+	r.Labels = memory.Policies
+	r.BarWidth = 7
+
+	r.update()
+
+	go func() {
+		for range time.NewTicker(r.updateInterval).C {
+			r.Lock()
+			r.update()
+			r.Unlock()
+		}
+	}()
+
+	return r
+}
+
+// update : replay the recorded references under every policy
+func (r *ReplacementWidget) update() {
+	faults := r.memory.PolicyFaults()
+
+	data := make([]float64, len(memory.Policies))
+	for i, name := range memory.Policies {
+		data[i] = float64(faults[name])
+	}
+
+	r.Data = data
+}
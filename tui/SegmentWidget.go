@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/sched"
+)
+
+// SegmentWidget : segment layout of every process
+type SegmentWidget struct {
+	*widgets.List
+	updateInterval time.Duration
+	kernel         *sched.Kernel
+}
+
+func NewSegmentWidget(k *sched.Kernel) *SegmentWidget {
+	s := &SegmentWidget{
+		List:           widgets.NewList(),
+		updateInterval: time.Second,
+		kernel:         k,
+	}
+	s.Title = " Segments "
+	s.WrapText = false
+
+	s.update()
+
+	go func() {
+		for range time.NewTicker(s.updateInterval).C {
+			s.Lock()
+			s.update()
+			s.Unlock()
+		}
+	}()
+
+	return s
+}
+
+// update : base, limit and protection of each segment a process has, by PID
+func (s *SegmentWidget) update() {
+	if !s.kernel.Mem.Segmented {
+		s.Rows = []string{"segmentation off"}
+		return
+	}
+
+	tables := s.kernel.Segments()
+
+	pids := make([]int, 0, len(tables))
+	for pid := range tables {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+
+	rows := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		segments := []string{}
+		for _, seg := range tables[pid] {
+			if seg.Limit > 0 {
+				segments = append(segments, fmt.Sprintf("%s %d+%d %s", seg.Name, seg.Base, seg.Limit, protection(seg.Protection)))
+			}
+		}
+
+		rows = append(rows, fmt.Sprintf("%d: %s", pid, strings.Join(segments, " | ")))
+	}
+
+	s.Rows = rows
+}
+
+// protection : rwx string for protection bits
+func protection(prot int) string {
+	bits := []byte("---")
+	if prot&memory.ProtRead != 0 {
+		bits[0] = 'r'
+	}
+
+	if prot&memory.ProtWrite != 0 {
+		bits[1] = 'w'
+	}
+
+	if prot&memory.ProtExec != 0 {
+		bits[2] = 'x'
+	}
+
+	return string(bits)
+}
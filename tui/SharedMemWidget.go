@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/memory"
+)
+
+// SharedMemWidget : processes attached to each shared memory segment, next to the mailboxes to compare the two
+type SharedMemWidget struct {
+	*widgets.BarChart
+	updateInterval time.Duration
+	memory         *memory.Memory
+}
+
+func NewSharedMemWidget(m *memory.Memory) *SharedMemWidget {
+	s := &SharedMemWidget{
+		BarChart:       widgets.NewBarChart(),
+		updateInterval: time.Second,
+		memory:         m,
+	}
+	s.BarWidth = 3
+
+	s.update()
+
+	go func() {
+		for range time.NewTicker(s.updateInterval).C {
+			s.Lock()
+			s.update()
+			s.Unlock()
+		}
+	}()
+
+	return s
+}
+
+// update : attached processes by key
+func (s *SharedMemWidget) update() {
+	segments := s.memory.SharedSegments()
+
+	s.Title = fmt.Sprintf(" Shared Memory (%d segments) ", len(segments))
+	s.Labels = make([]string, len(segments))
+	s.Data = make([]float64, len(segments))
+
+	// Nothing to scale to until a process attaches
+	s.MaxVal = 1
+	for i, seg := range segments {
+		s.Labels[i] = strconv.Itoa(seg.Key)
+		s.Data[i] = float64(seg.Attached)
+
+		if seg.Attached > 0 {
+			s.MaxVal = 0
+		}
+	}
+}
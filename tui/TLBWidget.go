@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/sched"
+)
+
+type TLBWidget struct {
+	*widgets.List
+	updateInterval time.Duration
+	kernel         *sched.Kernel
+}
+
+func NewTLBWidget(k *sched.Kernel) *TLBWidget {
+	t := &TLBWidget{
+		List:           widgets.NewList(),
+		updateInterval: time.Second,
+		kernel:         k,
+	}
+	t.Title = " TLB "
+	t.WrapText = false
+
+	t.update()
+
+	go func() {
+		for range time.NewTicker(t.updateInterval).C {
+			t.Lock()
+			t.update()
+			t.Unlock()
+		}
+	}()
+
+	return t
+}
+
+// update : hit rate and effective access time of each CPU's TLB
+func (t *TLBWidget) update() {
+	rows := []string{}
+
+	for _, s := range t.kernel.Schedulers {
+		if s.TLB == nil {
+			continue
+		}
+
+		hits, misses, flushes := s.TLB.Stats()
+		rows = append(rows,
+			fmt.Sprintf("CPU %d: %5.1f%% hits, %.1fns EAT", s.ID+1, 100*s.TLB.HitRate(), s.TLB.EffectiveAccessTime()),
+			fmt.Sprintf("  %d hits, %d misses, %d flushes", hits, misses, flushes),
+		)
+	}
+
+	if len(rows) == 0 {
+		rows = []string{"no TLB"}
+	}
+
+	t.Rows = rows
+}
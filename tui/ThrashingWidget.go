@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/sched"
+)
+
+// ThrashingWidget : page fault rate seen with each degree of multiprogramming
+type ThrashingWidget struct {
+	*widgets.Plot
+	updateInterval time.Duration
+	kernel         *sched.Kernel
+}
+
+func NewThrashingWidget(k *sched.Kernel) *ThrashingWidget {
+	t := &ThrashingWidget{
+		Plot:           widgets.NewPlot(),
+		updateInterval: time.Second,
+		kernel:         k,
+	}
+	t.PlotType = widgets.ScatterPlot
+	t.HorizontalScale = 3
+
+	t.update()
+
+	go func() {
+		for range time.NewTicker(t.updateInterval).C {
+			t.Lock()
+			t.update()
+			t.Unlock()
+		}
+	}()
+
+	return t
+}
+
+// update : average fault rate for one process in memory, two processes and so on
+func (t *ThrashingWidget) update() {
+	if t.kernel.PFF == nil {
+		t.Title = " Fault Rate vs Multiprogramming (off) "
+		t.Data = [][]float64{{}}
+		t.MaxVal = 1
+		return
+	}
+
+	curve := t.kernel.PFF.Curve()
+
+	t.Title = fmt.Sprintf(" Fault Rate vs Multiprogramming (%d suspended) ", t.kernel.PFF.Suspended())
+	t.Data = [][]float64{curve}
+
+	// Nothing to scale to until a fault happens
+	t.MaxVal = 1
+	for _, rate := range curve {
+		if rate > 0 {
+			t.MaxVal = 0
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	"github.com/jonaylor89/John_Naylor_CMSC312_2019/sched"
+)
+
+type TreeWidget struct {
+	*widgets.Tree
+	updateInterval time.Duration
+	kernel         *sched.Kernel
+}
+
+// treeValue : label for a process in the tree
+type treeValue string
+
+func (v treeValue) String() string {
+	return string(v)
+}
+
+func NewTreeWidget(k *sched.Kernel) *TreeWidget {
+	t := &TreeWidget{
+		Tree:           widgets.NewTree(),
+		updateInterval: time.Second,
+		kernel:         k,
+	}
+	t.Title = " Process Tree "
+	t.WrapText = false
+
+	t.update()
+
+	go func() {
+		for range time.NewTicker(t.updateInterval).C {
+			t.Lock()
+			t.update()
+			t.Unlock()
+		}
+	}()
+
+	return t
+}
+
+// update : init at the root with every process under its parent
+func (t *TreeWidget) update() {
+	t.SetNodes([]*widgets.TreeNode{toTreeNode(t.kernel.ProcessTree())})
+}
+
+// toTreeNode : convert a process and its children to tree nodes
+func toTreeNode(p *sched.ProcessNode) *widgets.TreeNode {
+	label := fmt.Sprintf("%d %s", p.PID, p.Name)
+	if p.Zombie {
+		label += " <zombie>"
+	}
+
+	node := &widgets.TreeNode{
+		Value:    treeValue(label),
+		Expanded: true,
+		Nodes:    make([]*widgets.TreeNode, len(p.Children)),
+	}
+
+	for i, child := range p.Children {
+		node.Nodes[i] = toTreeNode(child)
+	}
+
+	return node
+}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
@@ -20,11 +21,22 @@ const (
 
 var (
 	header   *widgets.Paragraph
-	readys   *ProcWidget
+	readys   []*ProcWidget
 	waitings *ProcWidget
 	mems     *MemWidget
+	policies *ReplacementWidget
+	tlbs     *TLBWidget
+	thrash   *ThrashingWidget
+	segs     *SegmentWidget
+	kmem     *KernelMemWidget
+	mails    *MailWidget
+	shm      *SharedMemWidget
+	events   *EventWidget
+	locks    *DeadlockWidget
+	tree     *TreeWidget
 	shell    *TextBox
 	grid     *ui.Grid
+	kernel   *sched.Kernel
 
 	updateInterval = time.Second / 10
 
@@ -39,21 +51,65 @@ var (
 	)
 )
 
-func InitWidgets(s *sched.Scheduler) {
-	mems = NewMemWidget(s.Mem)
+func InitWidgets(k *sched.Kernel) {
+	kernel = k
+
+	mems = NewMemWidget(k.Mem)
 	mems.SetRect(0, 0, 25, 5)
 
+	tlbs = NewTLBWidget(k)
+	tlbs.SetRect(0, 0, 25, 5)
+
+	policies = NewReplacementWidget(k.Mem)
+	policies.SetRect(0, 0, 25, 5)
+
+	thrash = NewThrashingWidget(k)
+	thrash.SetRect(0, 0, 25, 5)
+
+	segs = NewSegmentWidget(k)
+	segs.SetRect(0, 0, 25, 5)
+
+	kmem = NewKernelMemWidget(k.Mem)
+	kmem.SetRect(0, 0, 25, 5)
+
 	header = widgets.NewParagraph()
 	header.Text = " CMSC 312 Operating System Simulator "
 	header.SetRect(0, 0, 25, 5)
 
-	readys = NewProcWidget(&s.ReadyQ)
-	readys.Title = " Ready Processes "
-	readys.TextStyle = ui.NewStyle(ui.ColorYellow)
-	// readys.WrapText = false
-	readys.SetRect(0, 0, 25, 8)
+	mails = NewMailWidget(k)
+	mails.SetRect(0, 0, 25, 5)
+
+	shm = NewSharedMemWidget(k.Mem)
+	shm.SetRect(0, 0, 25, 5)
+
+	events = NewEventWidget(k)
+	events.SetRect(0, 0, 25, 5)
+
+	locks = NewDeadlockWidget(k.Detector)
+	locks.SetRect(0, 0, 25, 5)
+
+	tree = NewTreeWidget(k)
+	tree.SetRect(0, 0, 25, 5)
 
-	waitings = NewProcWidget(&s.WaitingQ)
+	// One ready queue for each CPU
+	readys = make([]*ProcWidget, len(k.Schedulers))
+	for i, s := range k.Schedulers {
+		readys[i] = NewProcWidget(s.ReadySnapshot)
+		readys[i].Title = fmt.Sprintf(" CPU %d Ready Processes ", s.ID+1)
+		readys[i].TextStyle = ui.NewStyle(ui.ColorYellow)
+		// readys[i].WrapText = false
+		readys[i].SetRect(0, 0, 25, 8)
+	}
+
+	// Processes waiting for memory on any CPU
+	waitings = NewProcWidget(func() []sched.ProcessInfo {
+		waiting := []sched.ProcessInfo{}
+		for _, s := range k.Schedulers {
+			waiting = append(waiting, s.WaitingSnapshot()...)
+		}
+
+		return waiting
+	})
 	waitings.Title = " Waiting Processes "
 	waitings.TextStyle = ui.NewStyle(ui.ColorYellow)
 	// waitings.WrapText = false
@@ -67,7 +123,7 @@ func InitWidgets(s *sched.Scheduler) {
 
 }
 
-func Map(vs []*sched.Process, f func(*sched.Process) string) []string {
+func Map(vs []sched.ProcessInfo, f func(sched.ProcessInfo) string) []string {
 	vsm := make([]string, len(vs))
 	for i, v := range vs {
 		vsm[i] = f(v)
@@ -80,17 +136,31 @@ func RenderTUI() {
 
 	grid = ui.NewGrid()
 
+	// Ready queues side by side with the waiting queue and thrashing plot at the end
+	queues := make([]interface{}, 0, len(readys)+2)
+	for _, ready := range readys {
+		queues = append(queues, ui.NewCol(1.0/float64(len(readys)+2), ready))
+	}
+	queues = append(queues, ui.NewCol(1.0/float64(len(readys)+2), waitings))
+	queues = append(queues, ui.NewCol(1.0/float64(len(readys)+2), thrash))
+
 	// et grid dimensions
 	grid.Set(
 		ui.NewRow(1.0/3,
-			ui.NewCol(1.0/1, header),
-		),
-		ui.NewRow(1.0/3,
-			ui.NewCol(1.0/2, readys),
-			ui.NewCol(1.0/2, waitings),
+			ui.NewCol(1.0/6, header),
+			ui.NewCol(1.0/6, policies),
+			ui.NewCol(1.0/6, mails),
+			ui.NewCol(1.0/6, segs),
+			ui.NewCol(1.0/6, shm),
+			ui.NewCol(1.0/6, locks),
 		),
+		ui.NewRow(1.0/3, queues...),
 		ui.NewRow(1.0/3,
-			ui.NewCol(1.0/1, mems),
+			ui.NewCol(2.0/9, mems),
+			ui.NewCol(1.0/9, tlbs),
+			ui.NewCol(1.0/6, tree),
+			ui.NewCol(1.0/6, kmem),
+			ui.NewCol(1.0/3, events),
 		),
 	)
 
@@ -126,11 +196,15 @@ func Launch(args []string, ch chan *sched.Process) bool {
 			break
 		}
 
-		err = sched.LoadTemplate(filename, numOfProc, ch)
+		err = sched.LoadTemplate(filename, numOfProc, kernel.Mem.Segmented, ch)
 		if err != nil {
 			break
 		}
 
+	case "check":
+		// Results show up in the kernel events
+		kernel.CheckMemory()
+
 	case "exit", "quit", "q", ":wq":
 		return true
 